@@ -18,7 +18,7 @@ func main() {
 	// Make the plan
 	plan, err := helm.NewPlan(*cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%w\n", err)
+		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
 	}
 
@@ -3,8 +3,11 @@ package helm
 import (
 	"fmt"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/pelotech/drone-helm3/internal/secrets"
 	"io"
+	"reflect"
 	"regexp"
+	"sort"
 )
 
 var justNumbers = regexp.MustCompile(`^\d+$`)
@@ -15,32 +18,39 @@ var justNumbers = regexp.MustCompile(`^\d+$`)
 // not have the `PLUGIN_` prefix. It may, however, be prefixed with the value in `$PLUGIN_PREFIX`.
 type Config struct {
 	// Configuration for drone-helm itself
-	Command            string   `envconfig:"HELM_COMMAND"`           // Helm command to run
-	DroneEvent         string   `envconfig:"DRONE_BUILD_EVENT"`      // Drone event that invoked this plugin.
-	UpdateDependencies bool     `split_words:"true"`                 // Call `helm dependency update` before the main command
-	AddRepos           []string `envconfig:"HELM_REPOS"`             // Call `helm repo add` before the main command
-	Prefix             string   ``                                   // Prefix to use when looking up secret env vars
-	Debug              bool     ``                                   // Generate debug output and pass --debug to all helm commands
-	Values             string   ``                                   // Argument to pass to --set in applicable helm commands
-	StringValues       string   `split_words:"true"`                 // Argument to pass to --set-string in applicable helm commands
-	ValuesFiles        []string `split_words:"true"`                 // Arguments to pass to --values in applicable helm commands
-	Namespace          string   ``                                   // Kubernetes namespace for all helm commands
-	KubeToken          string   `envconfig:"KUBERNETES_TOKEN"`       // Kubernetes authentication token to put in .kube/config
-	SkipTLSVerify      bool     `envconfig:"SKIP_TLS_VERIFY"`        // Put insecure-skip-tls-verify in .kube/config
-	Certificate        string   `envconfig:"KUBERNETES_CERTIFICATE"` // The Kubernetes cluster CA's self-signed certificate (must be base64-encoded)
-	APIServer          string   `envconfig:"API_SERVER"`             // The Kubernetes cluster's API endpoint
-	ServiceAccount     string   `split_words:"true"`                 // Account to use for connecting to the Kubernetes cluster
-	ChartVersion       string   `split_words:"true"`                 // Specific chart version to use in `helm upgrade`
-	DryRun             bool     `split_words:"true"`                 // Pass --dry-run to applicable helm commands
-	Wait               bool     ``                                   // Pass --wait to applicable helm commands
-	ReuseValues        bool     `split_words:"true"`                 // Pass --reuse-values to `helm upgrade`
-	Timeout            string   ``                                   // Argument to pass to --timeout in applicable helm commands
-	Chart              string   ``                                   // Chart argument to use in applicable helm commands
-	Release            string   ``                                   // Release argument to use in applicable helm commands
-	Force              bool     ``                                   // Pass --force to applicable helm commands
+	Command                string   `envconfig:"HELM_COMMAND"`           // Helm command to run
+	DroneEvent             string   `envconfig:"DRONE_BUILD_EVENT"`      // Drone event that invoked this plugin.
+	UpdateDependencies     bool     `split_words:"true"`                 // Call `helm dependency update` before the main command
+	AutoAddDependencyRepos bool     `split_words:"true"`                 // Call `helm repo add` for the chart's dependency repos before `helm dependency update`
+	AddRepos               []string `envconfig:"HELM_REPOS"`             // Call `helm repo add` before the main command
+	Prefix                 string   ``                                   // Prefix to use when looking up secret env vars
+	Debug                  bool     ``                                   // Generate debug output and pass --debug to all helm commands
+	Values                 string   ``                                   // Argument to pass to --set in applicable helm commands
+	StringValues           string   `split_words:"true"`                 // Argument to pass to --set-string in applicable helm commands
+	ValuesFiles            []string `split_words:"true"`                 // Arguments to pass to --values in applicable helm commands
+	SecretValuesFrom       string   `split_words:"true"`                 // Pass values from stdin or this file descriptor number to applicable helm commands
+	Namespace              string   ``                                   // Kubernetes namespace for all helm commands
+	KubeToken              string   `envconfig:"KUBERNETES_TOKEN"`       // Kubernetes authentication token to put in .kube/config
+	SkipTLSVerify          bool     `envconfig:"SKIP_TLS_VERIFY"`        // Put insecure-skip-tls-verify in .kube/config
+	Certificate            string   `envconfig:"KUBERNETES_CERTIFICATE"` // The Kubernetes cluster CA's self-signed certificate (must be base64-encoded)
+	APIServer              string   `envconfig:"API_SERVER"`             // The Kubernetes cluster's API endpoint
+	ServiceAccount         string   `split_words:"true"`                 // Account to use for connecting to the Kubernetes cluster
+	ChartVersion           string   `split_words:"true"`                 // Specific chart version to use in `helm upgrade`
+	DryRun                 bool     `split_words:"true"`                 // Pass --dry-run to applicable helm commands
+	Wait                   bool     ``                                   // Pass --wait to applicable helm commands
+	ReuseValues            bool     `split_words:"true"`                 // Pass --reuse-values to `helm upgrade`
+	Timeout                string   ``                                   // Argument to pass to --timeout in applicable helm commands
+	Chart                  string   ``                                   // Chart argument to use in applicable helm commands
+	ChartRepo              string   `split_words:"true"`                 // Argument to pass to --repo in `helm show`
+	OutputFile             string   `split_words:"true"`                 // File to write `helm show` output to
+	Release                string   ``                                   // Release argument to use in applicable helm commands
+	Force                  bool     ``                                   // Pass --force to applicable helm commands
+	CaptureHookLogs        bool     `split_words:"true"`                 // Print the logs of the release's install and upgrade hooks after `helm upgrade`
 
 	Stdout io.Writer `ignored:"true"`
 	Stderr io.Writer `ignored:"true"`
+
+	resolvedSecrets map[string]string // Settings that were resolved from secret:// references, keyed by e.g. "Values" or "ValuesFiles[1]"
 }
 
 // NewConfig creates a Config and reads environment variables into it, accounting for several possible formats.
@@ -65,6 +75,14 @@ func NewConfig(stdout, stderr io.Writer) (*Config, error) {
 		}
 	}
 
+	if cfg.AutoAddDependencyRepos && !cfg.UpdateDependencies {
+		return nil, fmt.Errorf("auto_add_dependency_repos requires update_dependencies to be true")
+	}
+
+	if err := cfg.resolveSecrets(secrets.NewResolver()); err != nil {
+		return nil, err
+	}
+
 	if justNumbers.MatchString(cfg.Timeout) {
 		cfg.Timeout = fmt.Sprintf("%ss", cfg.Timeout)
 	}
@@ -76,9 +94,100 @@ func NewConfig(stdout, stderr io.Writer) (*Config, error) {
 	return &cfg, nil
 }
 
+// resolveSecrets replaces any `secret://provider/path` values in the config's string and list settings with the
+// secrets they refer to, and records which settings it replaced so logDebug can redact them.
+func (cfg *Config) resolveSecrets(resolver *secrets.Resolver) error {
+	cfg.resolvedSecrets = make(map[string]string)
+
+	val := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		name := val.Type().Field(i).Name
+		if !field.CanSet() {
+			continue // unexported fields aren't settings
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			if err := cfg.resolveSecret(resolver, field, name, name); err != nil {
+				return err
+			}
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			for j := 0; j < field.Len(); j++ {
+				if err := cfg.resolveSecret(resolver, field.Index(j), name, fmt.Sprintf("%s[%d]", name, j)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (cfg *Config) resolveSecret(resolver *secrets.Resolver, field reflect.Value, name, key string) error {
+	if !secrets.IsReference(field.String()) {
+		return nil
+	}
+
+	resolved, err := resolver.Resolve(field.String())
+	if err != nil {
+		return fmt.Errorf("while resolving %s: %w", name, err)
+	}
+	field.SetString(resolved)
+	cfg.resolvedSecrets[key] = resolved
+
+	return nil
+}
+
 func (cfg Config) logDebug() {
 	if cfg.KubeToken != "" {
 		cfg.KubeToken = "(redacted)"
 	}
+
+	// Drone only masks the secrets it injected itself, so values fetched from a secret store must be censored here.
+	val := reflect.ValueOf(&cfg).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		name := val.Type().Field(i).Name
+		if !field.CanSet() {
+			continue // unexported fields aren't settings
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			if _, ok := cfg.resolvedSecrets[name]; ok {
+				field.SetString("(redacted)")
+			}
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			// cfg is a copy, but its slices share their contents with the original
+			censored := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(censored, field)
+			for j := 0; j < censored.Len(); j++ {
+				if _, ok := cfg.resolvedSecrets[fmt.Sprintf("%s[%d]", name, j)]; ok {
+					censored.Index(j).SetString("(redacted)")
+				}
+			}
+			field.Set(censored)
+		}
+	}
+	cfg.resolvedSecrets = nil
+
 	fmt.Fprintf(cfg.Stderr, "Generated config: %+v\n", cfg)
 }
+
+// secretValues returns the plaintext of every setting that was resolved from a secret store, longest first so that a
+// secret containing another one is censored as a whole.
+func (cfg Config) secretValues() []string {
+	var values []string
+	for _, value := range cfg.resolvedSecrets {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	return values
+}
@@ -123,6 +123,42 @@ func (suite *ConfigTestSuite) TestNewConfigSetsWriters() {
 	suite.Equal(stderr, cfg.Stderr)
 }
 
+func (suite *ConfigTestSuite) TestNewConfigResolvesSecrets() {
+	suite.setenv("DRONE_HELM3_TEST_TOKEN", "open sesame")
+	suite.setenv("DRONE_HELM3_TEST_VALUES", "password=swordfish")
+	suite.setenv("PLUGIN_KUBERNETES_TOKEN", "secret://env/DRONE_HELM3_TEST_TOKEN")
+	suite.setenv("PLUGIN_VALUES_FILES", "./a.yml,secret://env/DRONE_HELM3_TEST_VALUES")
+
+	cfg, err := NewConfig(&strings.Builder{}, &strings.Builder{})
+	suite.Require().NoError(err)
+
+	suite.Equal("open sesame", cfg.KubeToken)
+	suite.Equal([]string{"./a.yml", "password=swordfish"}, cfg.ValuesFiles)
+}
+
+func (suite *ConfigTestSuite) TestNewConfigSecretResolutionError() {
+	suite.unsetenv("DRONE_HELM3_TEST_TOKEN")
+	suite.setenv("PLUGIN_KUBERNETES_TOKEN", "secret://env/DRONE_HELM3_TEST_TOKEN")
+
+	_, err := NewConfig(&strings.Builder{}, &strings.Builder{})
+	suite.EqualError(err, "while resolving KubeToken: while resolving env secret 'DRONE_HELM3_TEST_TOKEN': "+
+		"environment variable DRONE_HELM3_TEST_TOKEN is not set")
+}
+
+func (suite *ConfigTestSuite) TestNewConfigAutoAddDependencyReposRequiresUpdateDependencies() {
+	suite.unsetenv("UPDATE_DEPENDENCIES")
+	suite.unsetenv("PLUGIN_UPDATE_DEPENDENCIES")
+	suite.setenv("PLUGIN_AUTO_ADD_DEPENDENCY_REPOS", "true")
+
+	_, err := NewConfig(&strings.Builder{}, &strings.Builder{})
+	suite.EqualError(err, "auto_add_dependency_repos requires update_dependencies to be true")
+
+	suite.setenv("PLUGIN_UPDATE_DEPENDENCIES", "true")
+	cfg, err := NewConfig(&strings.Builder{}, &strings.Builder{})
+	suite.Require().NoError(err)
+	suite.True(cfg.AutoAddDependencyRepos)
+}
+
 func (suite *ConfigTestSuite) TestLogDebug() {
 	suite.setenv("DEBUG", "true")
 	suite.setenv("HELM_COMMAND", "upgrade")
@@ -152,6 +188,27 @@ func (suite *ConfigTestSuite) TestLogDebugCensorsKubeToken() {
 	suite.Equal(kubeToken, cfg.KubeToken) // The actual config value should be left unchanged
 }
 
+func (suite *ConfigTestSuite) TestLogDebugCensorsResolvedSecrets() {
+	suite.setenv("DEBUG", "true")
+	suite.setenv("DRONE_HELM3_TEST_VALUES", "password=swordfish")
+	suite.setenv("DRONE_HELM3_TEST_VALUES_FILE", "./the_vault.yml")
+	suite.setenv("PLUGIN_VALUES", "secret://env/DRONE_HELM3_TEST_VALUES")
+	suite.setenv("PLUGIN_VALUES_FILES", "./public.yml,secret://env/DRONE_HELM3_TEST_VALUES_FILE")
+
+	stderr := strings.Builder{}
+	cfg, err := NewConfig(&strings.Builder{}, &stderr)
+	suite.Require().NoError(err)
+
+	suite.NotContains(stderr.String(), "swordfish")
+	suite.NotContains(stderr.String(), "the_vault")
+	suite.Contains(stderr.String(), "Values:(redacted)")
+	suite.Contains(stderr.String(), "ValuesFiles:[./public.yml (redacted)]")
+
+	// The actual config values should be left unchanged
+	suite.Equal("password=swordfish", cfg.Values)
+	suite.Equal([]string{"./public.yml", "./the_vault.yml"}, cfg.ValuesFiles)
+}
+
 func (suite *ConfigTestSuite) setenv(key, val string) {
 	orig, ok := os.LookupEnv(key)
 	if ok {
@@ -2,8 +2,13 @@ package helm
 
 import (
 	"fmt"
+	"github.com/pelotech/drone-helm3/internal/kube"
 	"github.com/pelotech/drone-helm3/internal/run"
+	"io"
+	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -19,9 +24,10 @@ type Step interface {
 
 // A Plan is a series of steps to perform.
 type Plan struct {
-	steps  []Step
-	cfg    Config
-	runCfg run.Config
+	steps        []Step
+	cfg          Config
+	runCfg       run.Config
+	secretValues io.ReadCloser
 }
 
 // NewPlan makes a plan for running a helm operation.
@@ -34,12 +40,25 @@ func NewPlan(cfg Config) (*Plan, error) {
 			StringValues: cfg.StringValues,
 			ValuesFiles:  cfg.ValuesFiles,
 			Namespace:    cfg.Namespace,
+			Secrets:      cfg.secretValues(),
 			Stdout:       cfg.Stdout,
 			Stderr:       cfg.Stderr,
 		},
 	}
 
-	p.steps = (*determineSteps(cfg))(cfg)
+	stepsFunc := determineSteps(cfg)
+
+	// Only upgrade and lint pass secret values to helm, so there's no need to open them for anything else.
+	if stepsFunc == &upgrade || stepsFunc == &lint {
+		secretValues, err := openSecretValues(cfg.SecretValuesFrom)
+		if err != nil {
+			return nil, err
+		}
+		p.secretValues = secretValues
+		p.runCfg.SecretValues = secretValues
+	}
+
+	p.steps = (*stepsFunc)(cfg)
 
 	for i, step := range p.steps {
 		if cfg.Debug {
@@ -47,6 +66,7 @@ func NewPlan(cfg Config) (*Plan, error) {
 		}
 
 		if err := step.Prepare(p.runCfg); err != nil {
+			p.closeSecretValues()
 			err = fmt.Errorf("while preparing %T step: %w", step, err)
 			return nil, err
 		}
@@ -55,6 +75,34 @@ func NewPlan(cfg Config) (*Plan, error) {
 	return &p, nil
 }
 
+// openSecretValues returns a reader for the values named by the secret_values_from setting, which can be "stdin" or
+// the number of a file descriptor that drone has opened for the plugin. Closing the reader doesn't close stdin.
+func openSecretValues(source string) (io.ReadCloser, error) {
+	switch source {
+	case "":
+		return nil, nil
+	case "stdin":
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+
+	// 0-2 are stdin, stdout, and stderr; stdin has its own keyword, and helm shouldn't read values from the others.
+	fd, err := strconv.Atoi(source)
+	if err != nil || fd < 3 {
+		return nil, fmt.Errorf("secret_values_from must be 'stdin' or a file descriptor number of 3 or more, not '%s'", source)
+	}
+
+	file := os.NewFile(uintptr(fd), fmt.Sprintf("fd%d", fd))
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not read secret values from file descriptor %d: %w", fd, err)
+	}
+	if !info.Mode().IsRegular() && info.Mode()&os.ModeNamedPipe == 0 {
+		file.Close()
+		return nil, fmt.Errorf("could not read secret values from file descriptor %d: not a regular file or pipe", fd)
+	}
+	return file, nil
+}
+
 // determineSteps is primarily for the tests' convenience: it allows testing the "which stuff should
 // we do" logic without building a config that meets all the steps' requirements.
 func determineSteps(cfg Config) *func(Config) []Step {
@@ -65,6 +113,8 @@ func determineSteps(cfg Config) *func(Config) []Step {
 		return &uninstall
 	case "lint":
 		return &lint
+	case "show-values", "show-chart", "show-readme":
+		return &show
 	case "help":
 		return &help
 	default:
@@ -81,6 +131,8 @@ func determineSteps(cfg Config) *func(Config) []Step {
 
 // Execute runs each step in the plan, aborting and reporting on error
 func (p *Plan) Execute() error {
+	defer p.closeSecretValues()
+
 	for i, step := range p.steps {
 		if p.cfg.Debug {
 			fmt.Fprintf(p.cfg.Stderr, "calling %T.Execute (step %d)\n", step, i)
@@ -94,13 +146,19 @@ func (p *Plan) Execute() error {
 	return nil
 }
 
+func (p *Plan) closeSecretValues() {
+	if p.secretValues != nil {
+		p.secretValues.Close()
+	}
+}
+
 var upgrade = func(cfg Config) []Step {
 	steps := initKube(cfg)
 	steps = append(steps, addRepos(cfg)...)
 	if cfg.UpdateDependencies {
 		steps = append(steps, depUpdate(cfg)...)
 	}
-	steps = append(steps, &run.Upgrade{
+	upgrade := &run.Upgrade{
 		Chart:        cfg.Chart,
 		Release:      cfg.Release,
 		ChartVersion: cfg.ChartVersion,
@@ -109,7 +167,15 @@ var upgrade = func(cfg Config) []Step {
 		ReuseValues:  cfg.ReuseValues,
 		Timeout:      cfg.Timeout,
 		Force:        cfg.Force,
-	})
+	}
+	// Hooks don't run during a dry run, so there would be nothing to capture.
+	if cfg.CaptureHookLogs && !cfg.DryRun {
+		upgrade.HookLogs = &run.HookLogs{
+			Release: cfg.Release,
+			Kube:    kubeConfig(cfg),
+		}
+	}
+	steps = append(steps, upgrade)
 
 	return steps
 }
@@ -139,6 +205,19 @@ var lint = func(cfg Config) []Step {
 	return steps
 }
 
+var show = func(cfg Config) []Step {
+	steps := addRepos(cfg)
+	steps = append(steps, &run.Show{
+		Subcommand:   strings.TrimPrefix(cfg.Command, "show-"),
+		Chart:        cfg.Chart,
+		ChartVersion: cfg.ChartVersion,
+		Repo:         cfg.ChartRepo,
+		OutputFile:   cfg.OutputFile,
+	})
+
+	return steps
+}
+
 var help = func(cfg Config) []Step {
 	help := &run.Help{
 		HelmCommand: cfg.Command,
@@ -160,6 +239,17 @@ func initKube(cfg Config) []Step {
 	}
 }
 
+// kubeConfig uses the same settings as initKube, so API clients connect to the cluster the same way helm does.
+func kubeConfig(cfg Config) kube.Config {
+	return kube.Config{
+		APIServer:     cfg.APIServer,
+		Token:         cfg.KubeToken,
+		Certificate:   cfg.Certificate,
+		SkipTLSVerify: cfg.SkipTLSVerify,
+		Namespace:     cfg.Namespace,
+	}
+}
+
 func addRepos(cfg Config) []Step {
 	steps := make([]Step, 0)
 	for _, repo := range cfg.AddRepos {
@@ -172,9 +262,16 @@ func addRepos(cfg Config) []Step {
 }
 
 func depUpdate(cfg Config) []Step {
-	return []Step{
-		&run.DepUpdate{
-			Chart: cfg.Chart,
-		},
+	steps := make([]Step, 0)
+	if cfg.AutoAddDependencyRepos {
+		steps = append(steps, &run.AddDependencyRepos{
+			Chart:           cfg.Chart,
+			ConfiguredRepos: cfg.AddRepos,
+		})
 	}
+	steps = append(steps, &run.DepUpdate{
+		Chart: cfg.Chart,
+	})
+
+	return steps
 }
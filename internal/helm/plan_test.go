@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 
+	"github.com/pelotech/drone-helm3/internal/kube"
 	"github.com/pelotech/drone-helm3/internal/run"
+	"github.com/pelotech/drone-helm3/internal/secrets"
 )
 
 type PlanTestSuite struct {
@@ -89,6 +95,155 @@ func (suite *PlanTestSuite) TestNewPlanAbortsOnError() {
 	suite.EqualError(err, "while preparing *helm.MockStep step: I'm starry Dave, aye, cat blew that")
 }
 
+func (suite *PlanTestSuite) TestNewPlanOpensSecretValues() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+	step := NewMockStep(ctrl)
+
+	origLint := lint
+	lint = func(cfg Config) []Step {
+		return []Step{step}
+	}
+	defer func() { lint = origLint }()
+
+	step.EXPECT().
+		Prepare(gomock.Any()).
+		Do(func(runCfg run.Config) {
+			suite.NotNil(runCfg.SecretValues)
+		})
+
+	cfg := Config{
+		Command:          "lint",
+		SecretValuesFrom: "stdin",
+	}
+
+	plan, err := NewPlan(cfg)
+	suite.Require().NoError(err)
+	suite.NotNil(plan.runCfg.SecretValues)
+}
+
+func (suite *PlanTestSuite) TestNewPlanOnlyOpensSecretValuesForUpgradeAndLint() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+	step := NewMockStep(ctrl)
+
+	origHelp := help
+	help = func(cfg Config) []Step {
+		return []Step{step}
+	}
+	defer func() { help = origHelp }()
+
+	step.EXPECT().
+		Prepare(gomock.Any()).
+		Do(func(runCfg run.Config) {
+			suite.Nil(runCfg.SecretValues)
+		})
+
+	cfg := Config{
+		Command:          "help",
+		SecretValuesFrom: "987",
+	}
+
+	plan, err := NewPlan(cfg)
+	suite.Require().NoError(err, "an unusable secret_values_from shouldn't matter when nothing reads it")
+	suite.Nil(plan.secretValues)
+}
+
+func (suite *PlanTestSuite) TestNewPlanCensorsSecretsInDebugOutput() {
+	os.Setenv("DRONE_HELM_TEST_VALUES", "db.password=hunter2")
+	defer os.Unsetenv("DRONE_HELM_TEST_VALUES")
+
+	stderr := strings.Builder{}
+	cfg := Config{
+		Command:      "lint",
+		Chart:        "./flow",
+		Debug:        true,
+		Values:       "secret://env/DRONE_HELM_TEST_VALUES",
+		StringValues: "image.tag=v1",
+		Stdout:       &strings.Builder{},
+		Stderr:       &stderr,
+	}
+	suite.Require().NoError(cfg.resolveSecrets(secrets.NewResolver()))
+
+	_, err := NewPlan(cfg)
+	suite.Require().NoError(err)
+	suite.Contains(stderr.String(), "--set (redacted) --set-string image.tag=v1")
+	suite.NotContains(stderr.String(), "hunter2")
+}
+
+func (suite *PlanTestSuite) TestOpenSecretValues() {
+	reader, err := openSecretValues("")
+	suite.NoError(err)
+	suite.Nil(reader, "no secret values should be read unless the setting is present")
+
+	reader, err = openSecretValues("stdin")
+	suite.NoError(err)
+	suite.Equal(ioutil.NopCloser(os.Stdin), reader)
+	suite.NoError(reader.Close())
+	_, err = os.Stdin.Stat()
+	suite.NoError(err, "closing the secret values shouldn't close stdin")
+
+	file, err := ioutil.TempFile("", "secret_values********.yml")
+	suite.Require().NoError(err)
+	defer os.Remove(file.Name())
+	defer file.Close()
+	_, err = file.WriteString("password: hunter2\n")
+	suite.Require().NoError(err)
+	_, err = file.Seek(0, 0)
+	suite.Require().NoError(err)
+
+	reader, err = openSecretValues(suite.dupFd(file))
+	suite.Require().NoError(err)
+	contents, err := ioutil.ReadAll(reader)
+	suite.NoError(reader.Close())
+	suite.Require().NoError(err)
+	suite.Equal("password: hunter2\n", string(contents))
+
+	pipeReader, pipeWriter, err := os.Pipe()
+	suite.Require().NoError(err)
+	defer pipeReader.Close()
+	_, err = pipeWriter.WriteString("password: swordfish\n")
+	suite.Require().NoError(err)
+	pipeWriter.Close()
+
+	reader, err = openSecretValues(suite.dupFd(pipeReader))
+	suite.Require().NoError(err)
+	contents, err = ioutil.ReadAll(reader)
+	suite.NoError(reader.Close())
+	suite.Require().NoError(err)
+	suite.Equal("password: swordfish\n", string(contents))
+}
+
+func (suite *PlanTestSuite) TestOpenSecretValuesErrors() {
+	_, err := openSecretValues("/dev/stdin")
+	suite.EqualError(err, "secret_values_from must be 'stdin' or a file descriptor number of 3 or more, not '/dev/stdin'")
+
+	_, err = openSecretValues("-3")
+	suite.EqualError(err, "secret_values_from must be 'stdin' or a file descriptor number of 3 or more, not '-3'")
+
+	_, err = openSecretValues("1")
+	suite.EqualError(err, "secret_values_from must be 'stdin' or a file descriptor number of 3 or more, not '1'")
+
+	_, err = openSecretValues("987")
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "could not read secret values from file descriptor 987")
+
+	dir, err := os.Open(os.TempDir())
+	suite.Require().NoError(err)
+	defer dir.Close()
+	fd := suite.dupFd(dir)
+	_, err = openSecretValues(fd)
+	suite.EqualError(err, fmt.Sprintf("could not read secret values from file descriptor %s: not a regular file or pipe", fd))
+}
+
+// dupFd returns the number of a duplicate of the file's descriptor, so openSecretValues can take ownership of it
+// without affecting the original.
+func (suite *PlanTestSuite) dupFd(file *os.File) string {
+	fd, err := syscall.Dup(int(file.Fd()))
+	suite.Require().NoError(err)
+	return strconv.Itoa(fd)
+}
+
 func (suite *PlanTestSuite) TestExecute() {
 	ctrl := gomock.NewController(suite.T())
 	defer ctrl.Finish()
@@ -112,6 +267,25 @@ func (suite *PlanTestSuite) TestExecute() {
 	suite.NoError(plan.Execute())
 }
 
+func (suite *PlanTestSuite) TestExecuteClosesSecretValues() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+	step := NewMockStep(ctrl)
+
+	secretValues := &closeRecorder{}
+	plan := Plan{
+		steps:        []Step{step},
+		secretValues: secretValues,
+	}
+
+	step.EXPECT().
+		Execute(gomock.Any()).
+		Return(fmt.Errorf("the coffee machine is on fire"))
+
+	suite.Error(plan.Execute())
+	suite.True(secretValues.closed, "secret values should be closed even when a step fails")
+}
+
 func (suite *PlanTestSuite) TestExecuteAbortsOnError() {
 	ctrl := gomock.NewController(suite.T())
 	defer ctrl.Finish()
@@ -167,6 +341,40 @@ func (suite *PlanTestSuite) TestUpgrade() {
 	suite.Equal(expected, upgrade)
 }
 
+func (suite *PlanTestSuite) TestUpgradeWithCaptureHookLogs() {
+	cfg := Config{
+		Chart:           "billboard_top_100",
+		Release:         "post_malone_circles",
+		CaptureHookLogs: true,
+		APIServer:       "98.765.43.21",
+		KubeToken:       "b2YgbXkgYWZmZWN0aW9u",
+		Certificate:     "cHJvY2xhaW1zIHdvbmRlcmZ1bCBmcmllbmRzaGlw",
+		SkipTLSVerify:   true,
+		Namespace:       "charts",
+	}
+
+	steps := upgrade(cfg)
+	suite.Require().IsType(&run.Upgrade{}, steps[1])
+	step, _ := steps[1].(*run.Upgrade)
+
+	expected := &run.HookLogs{
+		Release: "post_malone_circles",
+		Kube: kube.Config{
+			APIServer:     "98.765.43.21",
+			Token:         "b2YgbXkgYWZmZWN0aW9u",
+			Certificate:   "cHJvY2xhaW1zIHdvbmRlcmZ1bCBmcmllbmRzaGlw",
+			SkipTLSVerify: true,
+			Namespace:     "charts",
+		},
+	}
+	suite.Equal(expected, step.HookLogs)
+
+	cfg.DryRun = true
+	steps = upgrade(cfg)
+	step, _ = steps[1].(*run.Upgrade)
+	suite.Nil(step.HookLogs, "hook logs shouldn't be captured during a dry run")
+}
+
 func (suite *PlanTestSuite) TestUpgradeWithUpdateDependencies() {
 	cfg := Config{
 		UpdateDependencies: true,
@@ -279,6 +487,26 @@ func (suite *PlanTestSuite) TestDepUpdate() {
 	suite.Equal(expected, update)
 }
 
+func (suite *PlanTestSuite) TestDepUpdateWithAutoAddDependencyRepos() {
+	cfg := Config{
+		UpdateDependencies:     true,
+		AutoAddDependencyRepos: true,
+		Chart:                  "scatterplot",
+		AddRepos:               []string{"axes=https://add.repos/axes"},
+	}
+
+	steps := depUpdate(cfg)
+	suite.Require().Equal(2, len(steps), "depUpdate should add dependency repos before updating")
+	suite.Require().IsType(&run.AddDependencyRepos{}, steps[0])
+	suite.IsType(&run.DepUpdate{}, steps[1])
+
+	expected := &run.AddDependencyRepos{
+		Chart:           "scatterplot",
+		ConfiguredRepos: []string{"axes=https://add.repos/axes"},
+	}
+	suite.Equal(expected, steps[0])
+}
+
 func (suite *PlanTestSuite) TestAddRepos() {
 	cfg := Config{
 		AddRepos: []string{
@@ -329,6 +557,40 @@ func (suite *PlanTestSuite) TestLintWithAddRepos() {
 	suite.IsType(&run.AddRepo{}, steps[0])
 }
 
+func (suite *PlanTestSuite) TestShow() {
+	cfg := Config{
+		Command:      "show-values",
+		Chart:        "bitnami/wordpress",
+		ChartVersion: "8.1.0",
+		ChartRepo:    "https://charts.bitnami.com/bitnami",
+		OutputFile:   "./upstream.yaml",
+	}
+
+	steps := show(cfg)
+	suite.Equal(1, len(steps))
+
+	want := &run.Show{
+		Subcommand:   "values",
+		Chart:        "bitnami/wordpress",
+		ChartVersion: "8.1.0",
+		Repo:         "https://charts.bitnami.com/bitnami",
+		OutputFile:   "./upstream.yaml",
+	}
+	suite.Equal(want, steps[0])
+}
+
+func (suite *PlanTestSuite) TestShowWithAddRepos() {
+	cfg := Config{
+		Command:  "show-readme",
+		AddRepos: []string{"bitnami=https://charts.bitnami.com/bitnami"},
+	}
+	steps := show(cfg)
+	suite.Require().Equal(2, len(steps), "show should have a step for each repo")
+	suite.IsType(&run.AddRepo{}, steps[0])
+	suite.IsType(&run.Show{}, steps[1])
+	suite.Equal("readme", steps[1].(*run.Show).Subcommand)
+}
+
 func (suite *PlanTestSuite) TestDeterminePlanUpgradeCommand() {
 	cfg := Config{
 		Command: "upgrade",
@@ -382,6 +644,17 @@ func (suite *PlanTestSuite) TestDeterminePlanLintCommand() {
 	suite.Same(&lint, stepsMaker)
 }
 
+func (suite *PlanTestSuite) TestDeterminePlanShowCommands() {
+	for _, command := range []string{"show-values", "show-chart", "show-readme"} {
+		cfg := Config{
+			Command: command,
+		}
+
+		stepsMaker := determineSteps(cfg)
+		suite.Same(&show, stepsMaker, fmt.Sprintf("for command '%s'", command))
+	}
+}
+
 func (suite *PlanTestSuite) TestDeterminePlanHelpCommand() {
 	cfg := Config{
 		Command: "help",
@@ -390,3 +663,13 @@ func (suite *PlanTestSuite) TestDeterminePlanHelpCommand() {
 	stepsMaker := determineSteps(cfg)
 	suite.Same(&help, stepsMaker)
 }
+
+type closeRecorder struct {
+	strings.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
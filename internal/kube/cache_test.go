@@ -0,0 +1,53 @@
+package kube
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type CacheTestSuite struct {
+	suite.Suite
+	origNow func() time.Time
+	clock   time.Time
+}
+
+func TestCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
+
+func (suite *CacheTestSuite) BeforeTest(_, _ string) {
+	suite.origNow = now
+	suite.clock = time.Date(2019, 12, 10, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return suite.clock }
+}
+
+func (suite *CacheTestSuite) AfterTest(_, _ string) {
+	now = suite.origNow
+}
+
+func (suite *CacheTestSuite) TestGetAndSet() {
+	c := newCache(time.Minute)
+
+	_, ok := c.get("pod/nginx")
+	suite.False(ok)
+
+	c.set("pod/nginx", "running")
+	val, ok := c.get("pod/nginx")
+	suite.True(ok)
+	suite.Equal("running", val)
+}
+
+func (suite *CacheTestSuite) TestEntriesExpire() {
+	c := newCache(time.Minute)
+	c.set("pod/nginx", "running")
+
+	suite.clock = suite.clock.Add(59 * time.Second)
+	_, ok := c.get("pod/nginx")
+	suite.True(ok, "entry should still be valid before its TTL elapses")
+
+	suite.clock = suite.clock.Add(2 * time.Second)
+	_, ok = c.get("pod/nginx")
+	suite.False(ok, "entry should expire after its TTL elapses")
+	suite.Empty(c.entries, "expired entries should be removed")
+}
@@ -0,0 +1,177 @@
+package kube
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// DefaultQPS is the sustained rate of requests a Client will make to the API server.
+	DefaultQPS float32 = 5
+	// DefaultBurst is the number of requests a Client can make in a burst above DefaultQPS.
+	DefaultBurst = 10
+	// DefaultCacheTTL is how long a Client remembers the results of cacheable lookups.
+	DefaultCacheTTL = 30 * time.Second
+)
+
+// Config contains the settings for connecting to a Kubernetes cluster. They are the same settings InitKube puts in the
+// kubeconfig file, so a Client talks to the same cluster, as the same user, as the helm commands do.
+type Config struct {
+	APIServer     string
+	Token         string
+	Certificate   string // The cluster CA's certificate, base64-encoded
+	SkipTLSVerify bool
+	Namespace     string
+
+	QPS      float32       // Defaults to DefaultQPS
+	Burst    int           // Defaults to DefaultBurst
+	CacheTTL time.Duration // Defaults to DefaultCacheTTL
+}
+
+// A Client is a rate-limited connection to the Kubernetes API, shared by every step that needs one.
+type Client struct {
+	Namespace string
+
+	clientset  kubernetes.Interface
+	discovery  discovery.CachedDiscoveryInterface
+	cache      *cache
+	streamLogs func(namespace, pod, container string) (io.ReadCloser, error)
+}
+
+var (
+	clients   = make(map[Config]*Client)
+	clientsMu sync.Mutex
+)
+
+// New returns a Client for the given Config. Calls with identical Configs return the same Client, so its rate limit and
+// cache are shared by all of its users.
+func New(cfg Config) (*Client, error) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if c, ok := clients[cfg]; ok {
+		return c, nil
+	}
+
+	restCfg, err := restConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not create kubernetes client: %w", err)
+	}
+
+	c := newClient(cfg, clientset)
+	clients[cfg] = c
+	return c, nil
+}
+
+// NewForClientset returns a Client that uses an existing clientset instead of connecting to a cluster, such as
+// client-go's fake clientset (see the kubetest package). If logs is nil, PodLogs streams logs from the clientset.
+func NewForClientset(cfg Config, clientset kubernetes.Interface, logs func(namespace, pod, container string) (io.ReadCloser, error)) *Client {
+	c := newClient(cfg, clientset)
+	if logs != nil {
+		c.streamLogs = logs
+	}
+	return c
+}
+
+func newClient(cfg Config, clientset kubernetes.Interface) *Client {
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	c := &Client{
+		Namespace: cfg.Namespace,
+		clientset: clientset,
+		discovery: memory.NewMemCacheClient(clientset.Discovery()),
+		cache:     newCache(ttl),
+	}
+	c.streamLogs = c.clientsetLogs
+	return c
+}
+
+// restConfig translates a Config into client-go's terms.
+func restConfig(cfg Config) (*rest.Config, error) {
+	if cfg.APIServer == "" {
+		return nil, errors.New("an API Server is needed to connect to kubernetes")
+	}
+	if cfg.Token == "" {
+		return nil, errors.New("token is needed to connect to kubernetes")
+	}
+
+	qps := cfg.QPS
+	if qps == 0 {
+		qps = DefaultQPS
+	}
+	burst := cfg.Burst
+	if burst == 0 {
+		burst = DefaultBurst
+	}
+
+	restCfg := &rest.Config{
+		Host:        cfg.APIServer,
+		BearerToken: cfg.Token,
+		QPS:         qps,
+		Burst:       burst,
+		// An explicit limiter (rather than just QPS and Burst) is shared by every REST client built from this config,
+		// so the typed clients and the discovery client draw from the same budget.
+		RateLimiter: flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+	}
+
+	if cfg.SkipTLSVerify {
+		restCfg.TLSClientConfig.Insecure = true
+	} else if cfg.Certificate != "" {
+		ca, err := base64.StdEncoding.DecodeString(cfg.Certificate)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode kubernetes certificate: %w", err)
+		}
+		restCfg.TLSClientConfig.CAData = ca
+	}
+
+	return restCfg, nil
+}
+
+// Clientset returns the underlying client-go clientset, for API calls the Client doesn't wrap.
+func (c *Client) Clientset() kubernetes.Interface {
+	return c.clientset
+}
+
+// Discovery returns a discovery client whose results are cached for the Client's lifetime.
+func (c *Client) Discovery() discovery.CachedDiscoveryInterface {
+	return c.discovery
+}
+
+// NamespaceExists reports whether the named namespace exists. Positive results are cached.
+func (c *Client) NamespaceExists(name string) (bool, error) {
+	key := "namespace/" + name
+	if _, ok := c.cache.get(key); ok {
+		return true, nil
+	}
+
+	_, err := c.clientset.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("could not look up namespace %s: %w", name, err)
+	}
+
+	c.cache.set(key, true)
+	return true, nil
+}
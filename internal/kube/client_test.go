@@ -0,0 +1,168 @@
+package kube
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type ClientTestSuite struct {
+	suite.Suite
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
+
+func (suite *ClientTestSuite) TestRestConfig() {
+	cfg := Config{
+		APIServer:   "https://kube.example.com",
+		Token:       "in the crisper drawer",
+		Certificate: "Y2VydGlmaWNhdGU=",
+	}
+
+	restCfg, err := restConfig(cfg)
+	suite.Require().NoError(err)
+
+	suite.Equal("https://kube.example.com", restCfg.Host)
+	suite.Equal("in the crisper drawer", restCfg.BearerToken)
+	suite.Equal([]byte("certificate"), restCfg.TLSClientConfig.CAData)
+	suite.False(restCfg.TLSClientConfig.Insecure)
+	suite.Equal(DefaultQPS, restCfg.QPS)
+	suite.Equal(DefaultBurst, restCfg.Burst)
+	suite.Require().NotNil(restCfg.RateLimiter)
+	suite.Equal(DefaultQPS, restCfg.RateLimiter.QPS())
+}
+
+func (suite *ClientTestSuite) TestRestConfigRateLimits() {
+	cfg := Config{
+		APIServer: "https://kube.example.com",
+		Token:     "in the crisper drawer",
+		QPS:       50,
+		Burst:     100,
+	}
+
+	restCfg, err := restConfig(cfg)
+	suite.Require().NoError(err)
+
+	suite.Equal(float32(50), restCfg.QPS)
+	suite.Equal(100, restCfg.Burst)
+	suite.Equal(float32(50), restCfg.RateLimiter.QPS())
+}
+
+func (suite *ClientTestSuite) TestRestConfigSkipTLSVerify() {
+	cfg := Config{
+		APIServer:     "https://kube.example.com",
+		Token:         "in the crisper drawer",
+		Certificate:   "Y2VydGlmaWNhdGU=",
+		SkipTLSVerify: true,
+	}
+
+	restCfg, err := restConfig(cfg)
+	suite.Require().NoError(err)
+
+	suite.True(restCfg.TLSClientConfig.Insecure)
+	suite.Nil(restCfg.TLSClientConfig.CAData, "CA data is incompatible with insecure mode")
+}
+
+func (suite *ClientTestSuite) TestRestConfigErrors() {
+	_, err := restConfig(Config{Token: "in the crisper drawer"})
+	suite.EqualError(err, "an API Server is needed to connect to kubernetes")
+
+	_, err = restConfig(Config{APIServer: "https://kube.example.com"})
+	suite.EqualError(err, "token is needed to connect to kubernetes")
+
+	_, err = restConfig(Config{
+		APIServer:   "https://kube.example.com",
+		Token:       "in the crisper drawer",
+		Certificate: "definitely not base64!",
+	})
+	suite.Error(err)
+	suite.Contains(err.Error(), "could not decode kubernetes certificate")
+}
+
+func (suite *ClientTestSuite) TestNewSharesClients() {
+	cfg := Config{
+		APIServer: "https://kube.example.com",
+		Token:     "in the crisper drawer",
+		Namespace: "produce",
+	}
+
+	one, err := New(cfg)
+	suite.Require().NoError(err)
+	two, err := New(cfg)
+	suite.Require().NoError(err)
+	suite.Same(one, two)
+	suite.Equal("produce", one.Namespace)
+
+	cfg.Namespace = "dairy"
+	three, err := New(cfg)
+	suite.Require().NoError(err)
+	suite.False(one == three, "different configs should get different clients")
+}
+
+func (suite *ClientTestSuite) TestNewForClientset() {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "lettuce", Namespace: "produce"}}
+	logs := func(namespace, pod, container string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(namespace + "/" + pod + "/" + container)), nil
+	}
+	c := NewForClientset(Config{Namespace: "produce"}, fake.NewSimpleClientset(pod), logs)
+
+	suite.Equal("produce", c.Namespace)
+	suite.IsType(&fake.Clientset{}, c.Clientset())
+	suite.NotNil(c.Discovery())
+
+	got, err := c.Clientset().CoreV1().Pods("produce").Get("lettuce", metav1.GetOptions{})
+	suite.Require().NoError(err)
+	suite.Equal(pod, got)
+
+	stream, err := c.PodLogs("lettuce", "leaf")
+	suite.Require().NoError(err)
+	contents, err := ioutil.ReadAll(stream)
+	suite.Require().NoError(err)
+	suite.Equal("produce/lettuce/leaf", string(contents))
+}
+
+func (suite *ClientTestSuite) TestNamespaceExists() {
+	c := newFake("", &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "produce"}})
+	clientset := c.Clientset().(*fake.Clientset)
+
+	exists, err := c.NamespaceExists("produce")
+	suite.Require().NoError(err)
+	suite.True(exists)
+
+	exists, err = c.NamespaceExists("produce")
+	suite.Require().NoError(err)
+	suite.True(exists)
+	suite.Len(clientset.Actions(), 1, "the second lookup should be served from the cache")
+
+	exists, err = c.NamespaceExists("dairy")
+	suite.Require().NoError(err)
+	suite.False(exists)
+
+	exists, err = c.NamespaceExists("dairy")
+	suite.Require().NoError(err)
+	suite.False(exists)
+	suite.Len(clientset.Actions(), 3, "negative lookups should not be cached")
+}
+
+func (suite *ClientTestSuite) TestCacheTTL() {
+	c := newFake("")
+	suite.Equal(DefaultCacheTTL, c.cache.ttl)
+
+	c = newClient(Config{CacheTTL: time.Hour}, fake.NewSimpleClientset())
+	suite.Equal(time.Hour, c.cache.ttl)
+}
+
+// newFake returns a Client backed by client-go's fake clientset, pre-populated with the given objects.
+func newFake(namespace string, objects ...runtime.Object) *Client {
+	return newClient(Config{Namespace: namespace}, fake.NewSimpleClientset(objects...))
+}
@@ -0,0 +1,24 @@
+// Package kubetest provides kube.Clients backed by client-go's in-memory fake clientset. It's for tests only: importing
+// it links the fake clientset into the binary.
+package kubetest
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pelotech/drone-helm3/internal/kube"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// NewClient returns a kube.Client backed by a fake clientset that's pre-populated with the given objects. The fake
+// clientset is available via Clientset() for inspecting the actions the code under test took. logs holds the logs that
+// PodLogs returns, keyed in `pod/container` form; containers without an entry have no logs.
+func NewClient(namespace string, logs map[string]string, objects ...runtime.Object) *kube.Client {
+	streamLogs := func(_, pod, container string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(strings.NewReader(logs[fmt.Sprintf("%s/%s", pod, container)])), nil
+	}
+	return kube.NewForClientset(kube.Config{Namespace: namespace}, fake.NewSimpleClientset(objects...), streamLogs)
+}
@@ -0,0 +1,46 @@
+package kubetest
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+type KubetestTestSuite struct {
+	suite.Suite
+}
+
+func TestKubetestTestSuite(t *testing.T) {
+	suite.Run(t, new(KubetestTestSuite))
+}
+
+func (suite *KubetestTestSuite) TestNewClient() {
+	c := NewClient("kitchen", nil, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "toaster", Namespace: "kitchen"}})
+
+	suite.Equal("kitchen", c.Namespace)
+	suite.IsType(&fake.Clientset{}, c.Clientset())
+
+	pod, err := c.Pod("toaster")
+	suite.Require().NoError(err)
+	suite.Equal("toaster", pod.Name)
+}
+
+func (suite *KubetestTestSuite) TestNewClientLogs() {
+	c := NewClient("kitchen", map[string]string{"toaster/heating-element": "ding!\n"})
+
+	logs, err := c.PodLogs("toaster", "heating-element")
+	suite.Require().NoError(err)
+	contents, err := ioutil.ReadAll(logs)
+	suite.Require().NoError(err)
+	suite.Equal("ding!\n", string(contents))
+
+	logs, err = c.PodLogs("toaster", "crumb-tray")
+	suite.Require().NoError(err)
+	contents, err = ioutil.ReadAll(logs)
+	suite.Require().NoError(err)
+	suite.Equal("", string(contents))
+}
@@ -0,0 +1,53 @@
+package kube
+
+import (
+	"fmt"
+	"io"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsNotFound reports whether an error from a Client means the requested object doesn't exist.
+func IsNotFound(err error) bool {
+	return apierrors.IsNotFound(err)
+}
+
+// Pod returns the named pod in the Client's namespace.
+func (c *Client) Pod(name string) (*corev1.Pod, error) {
+	return c.clientset.CoreV1().Pods(c.namespace()).Get(name, metav1.GetOptions{})
+}
+
+// JobPods returns the pods that were created by the named job in the Client's namespace.
+func (c *Client) JobPods(job string) ([]corev1.Pod, error) {
+	if _, err := c.clientset.BatchV1().Jobs(c.namespace()).Get(job, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+
+	pods, err := c.clientset.CoreV1().Pods(c.namespace()).List(metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%s", job),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+// PodLogs returns a stream of the logs of one container in the named pod.
+func (c *Client) PodLogs(pod, container string) (io.ReadCloser, error) {
+	return c.streamLogs(c.namespace(), pod, container)
+}
+
+func (c *Client) namespace() string {
+	if c.Namespace == "" {
+		return metav1.NamespaceDefault
+	}
+	return c.Namespace
+}
+
+// clientsetLogs streams pod logs from the API server. client-go's fake clientset can't stream logs, so Clients that use
+// one supply their own log source (see NewForClientset).
+func (c *Client) clientsetLogs(namespace, pod, container string) (io.ReadCloser, error) {
+	return c.clientset.CoreV1().Pods(namespace).GetLogs(pod, &corev1.PodLogOptions{Container: container}).Stream()
+}
@@ -0,0 +1,60 @@
+package kube
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type PodsTestSuite struct {
+	suite.Suite
+}
+
+func TestPodsTestSuite(t *testing.T) {
+	suite.Run(t, new(PodsTestSuite))
+}
+
+func (suite *PodsTestSuite) TestPod() {
+	c := newFake("kitchen", &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "toaster", Namespace: "kitchen"}})
+
+	pod, err := c.Pod("toaster")
+	suite.Require().NoError(err)
+	suite.Equal("toaster", pod.Name)
+
+	_, err = c.Pod("blender")
+	suite.True(IsNotFound(err))
+}
+
+func (suite *PodsTestSuite) TestPodUsesDefaultNamespace() {
+	c := newFake("", &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "toaster", Namespace: "default"}})
+
+	_, err := c.Pod("toaster")
+	suite.NoError(err)
+}
+
+func (suite *PodsTestSuite) TestJobPods() {
+	c := newFake("kitchen",
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "breakfast", Namespace: "kitchen"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "breakfast-x7k2p",
+			Namespace: "kitchen",
+			Labels:    map[string]string{"job-name": "breakfast"},
+		}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "lunch-q9d4r",
+			Namespace: "kitchen",
+			Labels:    map[string]string{"job-name": "lunch"},
+		}},
+	)
+
+	pods, err := c.JobPods("breakfast")
+	suite.Require().NoError(err)
+	suite.Require().Len(pods, 1)
+	suite.Equal("breakfast-x7k2p", pods[0].Name)
+
+	_, err = c.JobPods("dinner")
+	suite.True(IsNotFound(err), "a missing job should be reported as not found")
+}
@@ -0,0 +1,125 @@
+package run
+
+import (
+	"crypto/sha256"
+	"fmt"
+	yaml "gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var nonRepoNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// AddDependencyRepos is an execution step that calls `helm repo add` for each of a chart's dependency repositories
+// that isn't already configured.
+type AddDependencyRepos struct {
+	Chart           string
+	ConfiguredRepos []string // Repo specs that will already have been added, in `name=url` form
+
+	repos []*AddRepo
+}
+
+type chartDependencies struct {
+	Dependencies []struct {
+		Name       string `yaml:"name"`
+		Repository string `yaml:"repository"`
+	} `yaml:"dependencies"`
+}
+
+// Execute executes the `helm repo add` commands.
+func (a *AddDependencyRepos) Execute(cfg Config) error {
+	for _, repo := range a.repos {
+		if err := repo.Execute(cfg); err != nil {
+			return fmt.Errorf("while adding dependency repo '%s': %w", repo.Repo, err)
+		}
+	}
+	return nil
+}
+
+// Prepare reads the chart's Chart.yaml and Chart.lock and gets a `helm repo add` command ready for each new repo.
+func (a *AddDependencyRepos) Prepare(cfg Config) error {
+	if a.Chart == "" {
+		return fmt.Errorf("chart is required")
+	}
+
+	known := make(map[string]bool)
+	for _, spec := range a.ConfiguredRepos {
+		split := strings.SplitN(spec, "=", 2)
+		if len(split) == 2 {
+			known[normalizeRepoURL(split[1])] = true
+		}
+	}
+
+	a.repos = make([]*AddRepo, 0)
+	for _, filename := range []string{"Chart.yaml", "Chart.lock"} {
+		path := filepath.Join(a.Chart, filename)
+		urls, err := dependencyRepoURLs(path)
+		if os.IsNotExist(err) && filename == "Chart.lock" {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not read dependencies from %s: %w", path, err)
+		}
+
+		for _, url := range urls {
+			if known[normalizeRepoURL(url)] {
+				continue
+			}
+			known[normalizeRepoURL(url)] = true
+
+			if cfg.Debug {
+				fmt.Fprintf(cfg.Stderr, "found dependency repo %s in %s\n", url, path)
+			}
+
+			repo := &AddRepo{
+				Repo: fmt.Sprintf("%s=%s", repoName(url), url),
+			}
+			if err := repo.Prepare(cfg); err != nil {
+				return err
+			}
+			a.repos = append(a.repos, repo)
+		}
+	}
+
+	return nil
+}
+
+// dependencyRepoURLs returns the repository URLs in a Chart.yaml or Chart.lock that `helm repo add` can handle. Local
+// (file://) and OCI dependencies don't need a repo, and aliases (@name or alias:name) refer to repos that must already
+// be configured.
+func dependencyRepoURLs(path string) ([]string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := chartDependencies{}
+	if err := yaml.Unmarshal(contents, &deps); err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0)
+	for _, dep := range deps.Dependencies {
+		if strings.HasPrefix(dep.Repository, "http://") || strings.HasPrefix(dep.Repository, "https://") {
+			urls = append(urls, dep.Repository)
+		}
+	}
+	return urls, nil
+}
+
+func normalizeRepoURL(url string) string {
+	return strings.TrimRight(url, "/")
+}
+
+// repoName generates a repo name from its URL. Helm matches dependencies to repos by URL, so the name only needs to be
+// unique and stable. The readable part of the name can collide (e.g. for https://a.b/c and https://a-b/c), so it's
+// followed by a short hash of the URL.
+func repoName(url string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(url, "https://"), "http://")
+	name = nonRepoNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	sum := sha256.Sum256([]byte(normalizeRepoURL(url)))
+	return fmt.Sprintf("%s-%x", strings.Trim(name, "-"), sum[:4])
+}
@@ -0,0 +1,236 @@
+package run
+
+import (
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type AddDependencyReposTestSuite struct {
+	suite.Suite
+	ctrl            *gomock.Controller
+	mockCmd         *Mockcmd
+	originalCommand func(string, ...string) cmd
+	commandArgs     [][]string
+	chartDir        string
+}
+
+func (suite *AddDependencyReposTestSuite) BeforeTest(_, _ string) {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockCmd = NewMockcmd(suite.ctrl)
+	suite.commandArgs = nil
+
+	suite.originalCommand = command
+	command = func(path string, args ...string) cmd {
+		suite.commandArgs = append(suite.commandArgs, args)
+		return suite.mockCmd
+	}
+
+	var err error
+	suite.chartDir, err = ioutil.TempDir("", "chart")
+	suite.Require().NoError(err)
+}
+
+func (suite *AddDependencyReposTestSuite) AfterTest(_, _ string) {
+	suite.ctrl.Finish()
+	command = suite.originalCommand
+	os.RemoveAll(suite.chartDir)
+}
+
+func TestAddDependencyReposTestSuite(t *testing.T) {
+	suite.Run(t, new(AddDependencyReposTestSuite))
+}
+
+func (suite *AddDependencyReposTestSuite) TestPrepareAndExecute() {
+	suite.writeChartFile("Chart.yaml", `
+apiVersion: v2
+name: magic_school_bus
+version: 0.1.0
+dependencies:
+  - name: postgresql
+    version: 8.x.x
+    repository: https://charts.bitnami.com/bitnami
+  - name: redis
+    version: 10.x.x
+    repository: https://charts.bitnami.com/bitnami/
+  - name: frizzle
+    version: 1.0.0
+    repository: https://Kubernetes-Charts.storage.googleapis.com
+`)
+
+	stdout := strings.Builder{}
+	stderr := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+
+	suite.mockCmd.EXPECT().Stdout(&stdout).Times(2)
+	suite.mockCmd.EXPECT().Stderr(&stderr).Times(2)
+
+	a := AddDependencyRepos{
+		Chart: suite.chartDir,
+	}
+	suite.Require().NoError(a.Prepare(cfg))
+
+	suite.Equal([][]string{
+		{"repo", "add", "charts-bitnami-com-bitnami-54d2620b", "https://charts.bitnami.com/bitnami"},
+		{"repo", "add", "kubernetes-charts-storage-googleapis-com-e044f2fa", "https://Kubernetes-Charts.storage.googleapis.com"},
+	}, suite.commandArgs)
+
+	suite.mockCmd.EXPECT().Run().Times(2)
+	suite.NoError(a.Execute(cfg))
+}
+
+func (suite *AddDependencyReposTestSuite) TestPrepareSkipsConfiguredRepos() {
+	suite.writeChartFile("Chart.yaml", `
+dependencies:
+  - name: postgresql
+    repository: https://charts.bitnami.com/bitnami/
+  - name: frizzle
+    repository: https://charts.example.com/frizzle
+`)
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+
+	a := AddDependencyRepos{
+		Chart:           suite.chartDir,
+		ConfiguredRepos: []string{"bitnami=https://charts.bitnami.com/bitnami"},
+	}
+	suite.Require().NoError(a.Prepare(Config{}))
+
+	suite.Equal([][]string{
+		{"repo", "add", "charts-example-com-frizzle-276a01e8", "https://charts.example.com/frizzle"},
+	}, suite.commandArgs)
+}
+
+func (suite *AddDependencyReposTestSuite) TestPrepareSkipsLocalOCIAndAliasedRepos() {
+	suite.writeChartFile("Chart.yaml", `
+dependencies:
+  - name: common
+    repository: file://../common
+  - name: arnold
+    repository: oci://registry.example.com/charts
+  - name: wanda
+    repository: "@classmates"
+  - name: ralphie
+    repository: alias:classmates
+  - name: vendored
+`)
+
+	a := AddDependencyRepos{
+		Chart: suite.chartDir,
+	}
+	suite.Require().NoError(a.Prepare(Config{}))
+	suite.Empty(suite.commandArgs)
+	suite.NoError(a.Execute(Config{}))
+}
+
+func (suite *AddDependencyReposTestSuite) TestPrepareReadsChartLock() {
+	suite.writeChartFile("Chart.yaml", `
+dependencies:
+  - name: postgresql
+    repository: https://charts.bitnami.com/bitnami
+`)
+	suite.writeChartFile("Chart.lock", `
+dependencies:
+- name: postgresql
+  repository: https://charts.bitnami.com/bitnami
+  version: 8.1.2
+- name: liz
+  repository: https://charts.example.com/lizard
+  version: 0.0.1
+digest: sha256:0123456789abcdef
+`)
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+
+	a := AddDependencyRepos{
+		Chart: suite.chartDir,
+	}
+	suite.Require().NoError(a.Prepare(Config{}))
+
+	suite.Equal([][]string{
+		{"repo", "add", "charts-bitnami-com-bitnami-54d2620b", "https://charts.bitnami.com/bitnami"},
+		{"repo", "add", "charts-example-com-lizard-57b91091", "https://charts.example.com/lizard"},
+	}, suite.commandArgs)
+}
+
+func (suite *AddDependencyReposTestSuite) TestPrepareNamespaceAndDebugFlags() {
+	suite.writeChartFile("Chart.yaml", `
+dependencies:
+  - name: postgresql
+    repository: https://charts.bitnami.com/bitnami
+`)
+	stderr := strings.Builder{}
+	cfg := Config{
+		Debug:     true,
+		Namespace: "walkerville",
+		Stderr:    &stderr,
+	}
+
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().String().Return("helm repo add ...")
+
+	a := AddDependencyRepos{
+		Chart: suite.chartDir,
+	}
+	suite.Require().NoError(a.Prepare(cfg))
+
+	suite.Equal([][]string{
+		{"--namespace", "walkerville", "--debug", "repo", "add", "charts-bitnami-com-bitnami-54d2620b", "https://charts.bitnami.com/bitnami"},
+	}, suite.commandArgs)
+
+	chartFile := filepath.Join(suite.chartDir, "Chart.yaml")
+	want := fmt.Sprintf("found dependency repo https://charts.bitnami.com/bitnami in %s\nGenerated command: 'helm repo add ...'\n", chartFile)
+	suite.Equal(want, stderr.String())
+}
+
+func (suite *AddDependencyReposTestSuite) TestExecuteWrapsErrors() {
+	suite.writeChartFile("Chart.yaml", `
+dependencies:
+  - name: postgresql
+    repository: https://charts.bitnami.com/bitnami
+`)
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Run().Return(fmt.Errorf("the bus is shrinking"))
+
+	a := AddDependencyRepos{
+		Chart: suite.chartDir,
+	}
+	suite.Require().NoError(a.Prepare(Config{}))
+	suite.EqualError(a.Execute(Config{}),
+		"while adding dependency repo 'charts-bitnami-com-bitnami-54d2620b=https://charts.bitnami.com/bitnami': the bus is shrinking")
+}
+
+func (suite *AddDependencyReposTestSuite) TestPrepareRequiresChartYaml() {
+	a := AddDependencyRepos{
+		Chart: suite.chartDir,
+	}
+	err := a.Prepare(Config{})
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "could not read dependencies from")
+}
+
+func (suite *AddDependencyReposTestSuite) TestPrepareRequiresChart() {
+	a := AddDependencyRepos{}
+	suite.EqualError(a.Prepare(Config{}), "chart is required")
+}
+
+func (suite *AddDependencyReposTestSuite) TestRepoNamesAreUnique() {
+	suite.Equal("a-b-c-12ba8f2c", repoName("https://a.b/c"))
+	suite.Equal("a-b-c-e4549d32", repoName("https://a-b/c"))
+	suite.Equal(repoName("https://a.b/c"), repoName("https://a.b/c/"), "trailing slashes shouldn't affect the name")
+}
+
+func (suite *AddDependencyReposTestSuite) writeChartFile(name, contents string) {
+	err := ioutil.WriteFile(filepath.Join(suite.chartDir, name), []byte(contents), 0644)
+	suite.Require().NoError(err)
+}
@@ -45,7 +45,7 @@ func (a *AddRepo) Prepare(cfg Config) error {
 	a.cmd.Stderr(cfg.Stderr)
 
 	if cfg.Debug {
-		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", a.cmd.String())
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(a.cmd.String()))
 	}
 
 	return nil
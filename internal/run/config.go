@@ -2,6 +2,7 @@ package run
 
 import (
 	"io"
+	"strings"
 )
 
 // Config contains configuration applicable to all helm commands
@@ -10,7 +11,20 @@ type Config struct {
 	Values       string
 	StringValues string
 	ValuesFiles  []string
+	SecretValues io.Reader // Values to pass to helm on stdin, after any ValuesFiles
+	Secrets      []string  // Settings resolved from a secret store, which must not appear in debug output
 	Namespace    string
 	Stdout       io.Writer
 	Stderr       io.Writer
 }
+
+// censor replaces any of the config's Secrets in a debug message with "(redacted)". Drone only masks the secrets it
+// injected itself, so it can't do this for us.
+func (cfg Config) censor(msg string) string {
+	for _, secret := range cfg.Secrets {
+		if secret != "" {
+			msg = strings.Replace(msg, secret, "(redacted)", -1)
+		}
+	}
+	return msg
+}
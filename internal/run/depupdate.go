@@ -37,7 +37,7 @@ func (d *DepUpdate) Prepare(cfg Config) error {
 	d.cmd.Stderr(cfg.Stderr)
 
 	if cfg.Debug {
-		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", d.cmd.String())
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(d.cmd.String()))
 	}
 
 	return nil
@@ -34,7 +34,7 @@ func (h *Help) Prepare(cfg Config) error {
 	h.cmd.Stderr(cfg.Stderr)
 
 	if cfg.Debug {
-		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", h.cmd.String())
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(h.cmd.String()))
 	}
 
 	return nil
@@ -0,0 +1,210 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pelotech/drone-helm3/internal/kube"
+	yaml "gopkg.in/yaml.v2"
+	"io"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The hook events whose logs are relevant after `helm upgrade --install`.
+var upgradeHooks = []string{"pre-install", "post-install", "pre-upgrade", "post-upgrade"}
+
+const (
+	hookGone   = "--- %s hook %s/%s no longer exists (it may have been removed by its hook-delete-policy) ---\n"
+	hookNoPods = "--- %s hook %s/%s has no pods (they may have been cleaned up after it finished) ---\n"
+	hookStale  = "--- %s hook %s/%s, pod %s, is from an earlier release (created %s), so its logs are skipped ---\n"
+
+	// clockSkew is how much older than the upgrade a hook pod can be and still count as part of it, since pods'
+	// creation times come from the API server's clock rather than ours.
+	clockSkew = 30 * time.Second
+)
+
+// newKubeClient is a variable so tests can substitute a fake client.
+var newKubeClient = kube.New
+
+// HookLogs fetches the logs of a release's install and upgrade hooks and copies them into the build log. It isn't a
+// Step in its own right; Upgrade uses it so the logs are captured even when the upgrade fails.
+type HookLogs struct {
+	Release string
+	Kube    kube.Config
+
+	cmd      cmd
+	manifest *bytes.Buffer
+	client   *kube.Client
+	since    time.Time // When the upgrade started; pods created before then weren't part of it
+}
+
+type hookResource struct {
+	Kind     string `yaml:"kind"`
+	Metadata struct {
+		Name        string            `yaml:"name"`
+		Annotations map[string]string `yaml:"annotations"`
+	} `yaml:"metadata"`
+}
+
+// Prepare connects to the Kubernetes API and gets the `helm get hooks` command ready to execute.
+func (h *HookLogs) Prepare(cfg Config) error {
+	if h.Release == "" {
+		return fmt.Errorf("release is required")
+	}
+
+	var err error
+	h.client, err = newKubeClient(h.Kube)
+	if err != nil {
+		return err
+	}
+
+	args := make([]string, 0)
+
+	if cfg.Namespace != "" {
+		args = append(args, "--namespace", cfg.Namespace)
+	}
+	if cfg.Debug {
+		args = append(args, "--debug")
+	}
+
+	args = append(args, "get", "hooks", h.Release)
+
+	h.manifest = &bytes.Buffer{}
+	h.cmd = command(helmBin, args...)
+	h.cmd.Stdout(h.manifest)
+	h.cmd.Stderr(cfg.Stderr)
+
+	if cfg.Debug {
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(h.cmd.String()))
+	}
+
+	return nil
+}
+
+// Execute looks up the release's hooks and writes the logs of each hook's containers to stdout. Problems finding a
+// particular hook's pods or logs are reported in the output rather than stopping the rest of the hooks from printing.
+func (h *HookLogs) Execute(cfg Config) error {
+	if err := h.cmd.Run(); err != nil {
+		return fmt.Errorf("while running '%s': %w", h.cmd.String(), err)
+	}
+
+	hooks, err := parseHooks(h.manifest.Bytes())
+	if err != nil {
+		return fmt.Errorf("could not parse hooks for release %s: %w", h.Release, err)
+	}
+
+	for _, hook := range hooks {
+		if err := h.printLogs(cfg, hook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HookLogs) printLogs(cfg Config, hook hookResource) error {
+	kind := strings.ToLower(hook.Kind)
+	name := hook.Metadata.Name
+	events := hook.Metadata.Annotations["helm.sh/hook"]
+
+	var pods []string
+	switch hook.Kind {
+	case "Job":
+		jobPods, err := h.client.JobPods(name)
+		if kube.IsNotFound(err) {
+			fmt.Fprintf(cfg.Stdout, hookGone, events, kind, name)
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(cfg.Stdout, "--- could not find pods for %s hook %s/%s: %s ---\n", events, kind, name, err)
+			return nil
+		}
+		if len(jobPods) == 0 {
+			fmt.Fprintf(cfg.Stdout, hookNoPods, events, kind, name)
+			return nil
+		}
+		for _, pod := range jobPods {
+			pods = append(pods, pod.Name)
+		}
+	case "Pod":
+		pods = []string{name}
+	}
+
+	for _, podName := range pods {
+		pod, err := h.client.Pod(podName)
+		if kube.IsNotFound(err) {
+			fmt.Fprintf(cfg.Stdout, hookGone, events, kind, name)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(cfg.Stdout, "--- could not look up pod %s for %s hook %s/%s: %s ---\n", podName, events, kind, name, err)
+			continue
+		}
+
+		// Hook resources from an earlier release can outlive it (helm's default hook-delete-policy only removes them
+		// when the hook runs again), and hooks don't run at all if the upgrade fails before reaching them.
+		if !h.since.IsZero() && pod.CreationTimestamp.Time.Before(h.since.Add(-clockSkew)) {
+			fmt.Fprintf(cfg.Stdout, hookStale, events, kind, name, pod.Name, pod.CreationTimestamp.UTC().Format(time.RFC3339))
+			continue
+		}
+
+		// Init containers run first, so their logs come first.
+		containers := make([]corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+		containers = append(containers, pod.Spec.InitContainers...)
+		containers = append(containers, pod.Spec.Containers...)
+		for _, container := range containers {
+			fmt.Fprintf(cfg.Stdout, "--- logs for %s hook %s/%s, pod %s, container %s ---\n", events, kind, name, pod.Name, container.Name)
+
+			logs, err := h.client.PodLogs(pod.Name, container.Name)
+			if err != nil {
+				fmt.Fprintf(cfg.Stdout, "(could not fetch logs: %s)\n", err)
+				continue
+			}
+			_, err = io.Copy(cfg.Stdout, logs)
+			logs.Close()
+			if err != nil {
+				return fmt.Errorf("while copying logs for pod %s: %w", pod.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseHooks returns the Jobs and Pods in a `helm get hooks` manifest that run during an install or upgrade.
+func parseHooks(manifest []byte) ([]hookResource, error) {
+	hooks := make([]hookResource, 0)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(manifest))
+	for {
+		resource := hookResource{}
+		err := decoder.Decode(&resource)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if resource.Kind != "Job" && resource.Kind != "Pod" {
+			continue
+		}
+		if isUpgradeHook(resource.Metadata.Annotations["helm.sh/hook"]) {
+			hooks = append(hooks, resource)
+		}
+	}
+
+	return hooks, nil
+}
+
+func isUpgradeHook(events string) bool {
+	for _, event := range strings.Split(events, ",") {
+		for _, hook := range upgradeHooks {
+			if strings.TrimSpace(event) == hook {
+				return true
+			}
+		}
+	}
+	return false
+}
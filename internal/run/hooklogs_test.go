@@ -0,0 +1,431 @@
+package run
+
+import (
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/pelotech/drone-helm3/internal/kube"
+	"github.com/pelotech/drone-helm3/internal/kube/kubetest"
+	"github.com/stretchr/testify/suite"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const hooksManifest = `---
+# Source: sweeney/templates/migrate.yaml
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: sweeney-migrate
+  annotations:
+    "helm.sh/hook": pre-install,pre-upgrade
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: fleet-street/pies
+---
+# Source: sweeney/templates/smoke-test.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: sweeney-smoke-test
+  annotations:
+    "helm.sh/hook": post-upgrade
+spec:
+  containers:
+    - name: smoke
+      image: fleet-street/razor
+---
+# Source: sweeney/templates/test.yaml
+apiVersion: v1
+kind: Pod
+metadata:
+  name: sweeney-test
+  annotations:
+    "helm.sh/hook": test
+---
+# Source: sweeney/templates/config.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: sweeney-config
+  annotations:
+    "helm.sh/hook": pre-upgrade
+`
+
+type HookLogsTestSuite struct {
+	suite.Suite
+	ctrl              *gomock.Controller
+	mockCmd           *Mockcmd
+	originalCommand   func(string, ...string) cmd
+	originalNewClient func(kube.Config) (*kube.Client, error)
+	client            *kube.Client
+	kubeConfig        kube.Config
+}
+
+func (suite *HookLogsTestSuite) BeforeTest(_, _ string) {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockCmd = NewMockcmd(suite.ctrl)
+
+	suite.originalCommand = command
+	command = func(path string, args ...string) cmd { return suite.mockCmd }
+
+	logs := map[string]string{
+		"sweeney-migrate-b4rb3/migrate": "ERROR: relation \"pies\" already exists\n",
+		"sweeney-smoke-test/smoke":      "the shop is open\n",
+	}
+	suite.client = kubetest.NewClient("fleet-street", logs,
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "sweeney-migrate", Namespace: "fleet-street"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "sweeney-migrate-b4rb3",
+				Namespace: "fleet-street",
+				Labels:    map[string]string{"job-name": "sweeney-migrate"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "migrate"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "sweeney-smoke-test", Namespace: "fleet-street"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "smoke"}}},
+		},
+	)
+
+	suite.originalNewClient = newKubeClient
+	newKubeClient = func(cfg kube.Config) (*kube.Client, error) {
+		suite.kubeConfig = cfg
+		return suite.client, nil
+	}
+}
+
+func (suite *HookLogsTestSuite) AfterTest(_, _ string) {
+	suite.ctrl.Finish()
+	command = suite.originalCommand
+	newKubeClient = suite.originalNewClient
+}
+
+func TestHookLogsTestSuite(t *testing.T) {
+	suite.Run(t, new(HookLogsTestSuite))
+}
+
+func (suite *HookLogsTestSuite) TestPrepareAndExecute() {
+	stdout := strings.Builder{}
+	stderr := strings.Builder{}
+	cfg := Config{
+		Namespace: "fleet-street",
+		Stdout:    &stdout,
+		Stderr:    &stderr,
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.Equal(helmBin, path)
+		suite.Equal([]string{"--namespace", "fleet-street", "get", "hooks", "sweeney"}, args)
+
+		return suite.mockCmd
+	}
+
+	var manifest io.Writer
+	suite.mockCmd.EXPECT().
+		Stdout(gomock.Any()).
+		Do(func(w io.Writer) { manifest = w })
+	suite.mockCmd.EXPECT().
+		Stderr(&stderr)
+	suite.mockCmd.EXPECT().
+		Run().
+		Do(func() { fmt.Fprint(manifest, hooksManifest) })
+
+	h := HookLogs{
+		Release: "sweeney",
+		Kube:    kube.Config{APIServer: "https://kube.example.com", Namespace: "fleet-street"},
+	}
+
+	suite.Require().NoError(h.Prepare(cfg))
+	suite.Equal(kube.Config{APIServer: "https://kube.example.com", Namespace: "fleet-street"}, suite.kubeConfig)
+
+	suite.Require().NoError(h.Execute(cfg))
+
+	want := "--- logs for pre-install,pre-upgrade hook job/sweeney-migrate, pod sweeney-migrate-b4rb3, container migrate ---\n" +
+		"ERROR: relation \"pies\" already exists\n" +
+		"--- logs for post-upgrade hook pod/sweeney-smoke-test, pod sweeney-smoke-test, container smoke ---\n" +
+		"the shop is open\n"
+	suite.Equal(want, stdout.String())
+}
+
+func (suite *HookLogsTestSuite) TestExecuteReportsDeletedHooks() {
+	suite.client = kubetest.NewClient("fleet-street", nil)
+
+	stdout := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+	}
+
+	var manifest io.Writer
+	suite.mockCmd.EXPECT().
+		Stdout(gomock.Any()).
+		Do(func(w io.Writer) { manifest = w })
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().
+		Run().
+		Do(func() { fmt.Fprint(manifest, hooksManifest) })
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+
+	suite.Require().NoError(h.Prepare(cfg))
+	suite.Require().NoError(h.Execute(cfg))
+
+	want := "--- pre-install,pre-upgrade hook job/sweeney-migrate no longer exists (it may have been removed by its hook-delete-policy) ---\n" +
+		"--- post-upgrade hook pod/sweeney-smoke-test no longer exists (it may have been removed by its hook-delete-policy) ---\n"
+	suite.Equal(want, stdout.String())
+}
+
+func (suite *HookLogsTestSuite) TestExecuteReportsLookupErrors() {
+	suite.client.Clientset().(*fake.Clientset).PrependReactor("get", "jobs",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(batchv1.Resource("jobs"), "sweeney-migrate", fmt.Errorf("no pies for you"))
+		})
+
+	stdout := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+	}
+
+	var manifest io.Writer
+	suite.mockCmd.EXPECT().
+		Stdout(gomock.Any()).
+		Do(func(w io.Writer) { manifest = w })
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().
+		Run().
+		Do(func() { fmt.Fprint(manifest, hooksManifest) })
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+
+	suite.Require().NoError(h.Prepare(cfg))
+	suite.Require().NoError(h.Execute(cfg), "lookup errors shouldn't stop the other hooks' logs from printing")
+
+	output := stdout.String()
+	suite.Contains(output, "--- could not find pods for pre-install,pre-upgrade hook job/sweeney-migrate: ")
+	suite.Contains(output, "no pies for you")
+	suite.Contains(output, "--- logs for post-upgrade hook pod/sweeney-smoke-test, pod sweeney-smoke-test, container smoke ---\n"+
+		"the shop is open\n")
+}
+
+func (suite *HookLogsTestSuite) TestExecuteIncludesInitContainers() {
+	logs := map[string]string{
+		"sweeney-smoke-test/sharpen": "razors sharpened\n",
+		"sweeney-smoke-test/smoke":   "the shop is open\n",
+	}
+	suite.client = kubetest.NewClient("fleet-street", logs,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "sweeney-smoke-test", Namespace: "fleet-street"},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "sharpen"}},
+				Containers:     []corev1.Container{{Name: "smoke"}},
+			},
+		},
+	)
+
+	stdout := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+	}
+
+	var manifest io.Writer
+	suite.mockCmd.EXPECT().
+		Stdout(gomock.Any()).
+		Do(func(w io.Writer) { manifest = w })
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().
+		Run().
+		Do(func() { fmt.Fprint(manifest, hooksManifest) })
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+
+	suite.Require().NoError(h.Prepare(cfg))
+	suite.Require().NoError(h.Execute(cfg))
+
+	want := "--- pre-install,pre-upgrade hook job/sweeney-migrate no longer exists (it may have been removed by its hook-delete-policy) ---\n" +
+		"--- logs for post-upgrade hook pod/sweeney-smoke-test, pod sweeney-smoke-test, container sharpen ---\n" +
+		"razors sharpened\n" +
+		"--- logs for post-upgrade hook pod/sweeney-smoke-test, pod sweeney-smoke-test, container smoke ---\n" +
+		"the shop is open\n"
+	suite.Equal(want, stdout.String())
+}
+
+func (suite *HookLogsTestSuite) TestExecuteSkipsPodsFromEarlierReleases() {
+	logs := map[string]string{
+		"sweeney-migrate-b4rb3/migrate": "ERROR: relation \"pies\" already exists\n",
+		"sweeney-smoke-test/smoke":      "the shop is open\n",
+	}
+	suite.client = kubetest.NewClient("fleet-street", logs,
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "sweeney-migrate", Namespace: "fleet-street"}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "sweeney-migrate-b4rb3",
+				Namespace:         "fleet-street",
+				Labels:            map[string]string{"job-name": "sweeney-migrate"},
+				CreationTimestamp: metav1.NewTime(time.Date(2019, 12, 10, 8, 30, 0, 0, time.UTC)),
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "migrate"}}},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "sweeney-smoke-test",
+				Namespace:         "fleet-street",
+				CreationTimestamp: metav1.Now(),
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "smoke"}}},
+		},
+	)
+
+	stdout := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+	}
+
+	var manifest io.Writer
+	suite.mockCmd.EXPECT().
+		Stdout(gomock.Any()).
+		Do(func(w io.Writer) { manifest = w })
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().
+		Run().
+		Do(func() { fmt.Fprint(manifest, hooksManifest) })
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+
+	suite.Require().NoError(h.Prepare(cfg))
+	h.since = time.Now()
+	suite.Require().NoError(h.Execute(cfg))
+
+	want := "--- pre-install,pre-upgrade hook job/sweeney-migrate, pod sweeney-migrate-b4rb3, is from an earlier release " +
+		"(created 2019-12-10T08:30:00Z), so its logs are skipped ---\n" +
+		"--- logs for post-upgrade hook pod/sweeney-smoke-test, pod sweeney-smoke-test, container smoke ---\n" +
+		"the shop is open\n"
+	suite.Equal(want, stdout.String())
+}
+
+func (suite *HookLogsTestSuite) TestExecuteReportsJobsWithoutPods() {
+	suite.client = kubetest.NewClient("fleet-street", nil,
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "sweeney-migrate", Namespace: "fleet-street"}},
+	)
+
+	stdout := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+	}
+
+	var manifest io.Writer
+	suite.mockCmd.EXPECT().
+		Stdout(gomock.Any()).
+		Do(func(w io.Writer) { manifest = w })
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().
+		Run().
+		Do(func() { fmt.Fprint(manifest, hooksManifest) })
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+
+	suite.Require().NoError(h.Prepare(cfg))
+	suite.Require().NoError(h.Execute(cfg))
+
+	want := "--- pre-install,pre-upgrade hook job/sweeney-migrate has no pods (they may have been cleaned up after it finished) ---\n" +
+		"--- post-upgrade hook pod/sweeney-smoke-test no longer exists (it may have been removed by its hook-delete-policy) ---\n"
+	suite.Equal(want, stdout.String())
+}
+
+func (suite *HookLogsTestSuite) TestExecuteHelmError() {
+	suite.mockCmd.EXPECT().Stdout(gomock.Any())
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().
+		Run().
+		Return(fmt.Errorf("release: not found"))
+	suite.mockCmd.EXPECT().
+		String().
+		Return("helm get hooks sweeney")
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+
+	suite.Require().NoError(h.Prepare(Config{}))
+	suite.EqualError(h.Execute(Config{}), "while running 'helm get hooks sweeney': release: not found")
+}
+
+func (suite *HookLogsTestSuite) TestPrepareDebugFlag() {
+	stderr := strings.Builder{}
+	cfg := Config{
+		Debug:  true,
+		Stderr: &stderr,
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.mockCmd.EXPECT().
+			String().
+			Return(fmt.Sprintf("%s %s", path, strings.Join(args, " ")))
+
+		return suite.mockCmd
+	}
+	suite.mockCmd.EXPECT().Stdout(gomock.Any())
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+
+	suite.Require().NoError(h.Prepare(cfg))
+
+	want := fmt.Sprintf("Generated command: '%s --debug get hooks sweeney'\n", helmBin)
+	suite.Equal(want, stderr.String())
+}
+
+func (suite *HookLogsTestSuite) TestPrepareRequiresRelease() {
+	h := HookLogs{}
+	suite.EqualError(h.Prepare(Config{}), "release is required")
+}
+
+func (suite *HookLogsTestSuite) TestPrepareClientError() {
+	newKubeClient = func(cfg kube.Config) (*kube.Client, error) {
+		return nil, fmt.Errorf("the barber is out")
+	}
+
+	h := HookLogs{
+		Release: "sweeney",
+	}
+	suite.EqualError(h.Prepare(Config{}), "the barber is out")
+}
+
+func (suite *HookLogsTestSuite) TestParseHooks() {
+	hooks, err := parseHooks([]byte(hooksManifest))
+	suite.Require().NoError(err)
+	suite.Require().Len(hooks, 2, "only install and upgrade hooks that are Jobs or Pods should be included")
+
+	suite.Equal("Job", hooks[0].Kind)
+	suite.Equal("sweeney-migrate", hooks[0].Metadata.Name)
+	suite.Equal("Pod", hooks[1].Kind)
+	suite.Equal("sweeney-smoke-test", hooks[1].Metadata.Name)
+
+	hooks, err = parseHooks([]byte(""))
+	suite.Require().NoError(err)
+	suite.Empty(hooks)
+}
@@ -41,15 +41,21 @@ func (l *Lint) Prepare(cfg Config) error {
 	for _, vFile := range cfg.ValuesFiles {
 		args = append(args, "--values", vFile)
 	}
+	if cfg.SecretValues != nil {
+		args = append(args, "--values", "/dev/stdin")
+	}
 
 	args = append(args, l.Chart)
 
 	l.cmd = command(helmBin, args...)
 	l.cmd.Stdout(cfg.Stdout)
 	l.cmd.Stderr(cfg.Stderr)
+	if cfg.SecretValues != nil {
+		l.cmd.Stdin(cfg.SecretValues)
+	}
 
 	if cfg.Debug {
-		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", l.cmd.String())
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(l.cmd.String()))
 	}
 
 	return nil
@@ -109,6 +109,35 @@ func (suite *LintTestSuite) TestPrepareWithLintFlags() {
 	suite.Require().Nil(err)
 }
 
+func (suite *LintTestSuite) TestPrepareWithSecretValues() {
+	defer suite.ctrl.Finish()
+
+	secretValues := strings.NewReader("password: hunter2\n")
+	cfg := Config{
+		ValuesFiles:  []string{"/usr/local/overrides"},
+		SecretValues: secretValues,
+	}
+
+	l := Lint{
+		Chart: "./ireland/top_40",
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.Equal([]string{"lint",
+			"--values", "/usr/local/overrides",
+			"--values", "/dev/stdin",
+			"./ireland/top_40"}, args)
+
+		return suite.mockCmd
+	}
+
+	suite.mockCmd.EXPECT().Stdout(gomock.Any())
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().Stdin(secretValues)
+
+	suite.Require().NoError(l.Prepare(cfg))
+}
+
 func (suite *LintTestSuite) TestPrepareWithDebugFlag() {
 	defer suite.ctrl.Finish()
 
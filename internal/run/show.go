@@ -0,0 +1,88 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Show is an execution step that calls `helm show` when executed.
+type Show struct {
+	Subcommand   string // One of `values`, `chart`, or `readme`
+	Chart        string
+	ChartVersion string
+	Repo         string // Repository URL to use in place of a locally-configured repo
+	OutputFile   string // Where to write the output; defaults to stdout
+
+	cmd cmd
+}
+
+// Execute executes the `helm show` command.
+func (s *Show) Execute(cfg Config) error {
+	if s.OutputFile != "" {
+		if cfg.Debug {
+			fmt.Fprintf(cfg.Stderr, "writing helm show output to %s\n", s.OutputFile)
+		}
+		file, err := os.Create(s.OutputFile)
+		if err != nil {
+			return fmt.Errorf("could not open output file for writing: %w", err)
+		}
+		defer file.Close()
+		s.cmd.Stdout(file)
+	}
+	return s.cmd.Run()
+}
+
+// Prepare gets the Show ready to execute.
+func (s *Show) Prepare(cfg Config) error {
+	switch s.Subcommand {
+	case "values", "chart", "readme":
+	default:
+		return fmt.Errorf("unknown show subcommand '%s'", s.Subcommand)
+	}
+	if s.Chart == "" {
+		return fmt.Errorf("chart is required")
+	}
+	if s.Repo != "" && strings.HasPrefix(s.Chart, "oci://") {
+		return fmt.Errorf("chart_repo cannot be used with an OCI chart reference")
+	}
+
+	args := make([]string, 0)
+
+	if cfg.Namespace != "" {
+		args = append(args, "--namespace", cfg.Namespace)
+	}
+	if cfg.Debug {
+		args = append(args, "--debug")
+	}
+
+	args = append(args, "show", s.Subcommand)
+
+	if s.ChartVersion != "" {
+		args = append(args, "--version", s.ChartVersion)
+	}
+	if s.Repo != "" {
+		args = append(args, "--repo", s.Repo)
+	}
+
+	args = append(args, s.Chart)
+
+	// The output file isn't created until Execute, so a failure in an earlier step doesn't leave it truncated.
+	if s.OutputFile != "" {
+		dir := filepath.Dir(s.OutputFile)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("output file directory %s does not exist", dir)
+		}
+	}
+
+	s.cmd = command(helmBin, args...)
+	s.cmd.Stdout(cfg.Stdout)
+	s.cmd.Stderr(cfg.Stderr)
+
+	if cfg.Debug {
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(s.cmd.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package run
+
+import (
+	"fmt"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type ShowTestSuite struct {
+	suite.Suite
+	ctrl            *gomock.Controller
+	mockCmd         *Mockcmd
+	originalCommand func(string, ...string) cmd
+}
+
+func (suite *ShowTestSuite) BeforeTest(_, _ string) {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.mockCmd = NewMockcmd(suite.ctrl)
+
+	suite.originalCommand = command
+	command = func(path string, args ...string) cmd { return suite.mockCmd }
+}
+
+func (suite *ShowTestSuite) AfterTest(_, _ string) {
+	command = suite.originalCommand
+}
+
+func TestShowTestSuite(t *testing.T) {
+	suite.Run(t, new(ShowTestSuite))
+}
+
+func (suite *ShowTestSuite) TestPrepareAndExecute() {
+	defer suite.ctrl.Finish()
+
+	stdout := strings.Builder{}
+	stderr := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.Equal(helmBin, path)
+		suite.Equal([]string{"show", "values", "stable/sweet_baby_ray"}, args)
+
+		return suite.mockCmd
+	}
+	suite.mockCmd.EXPECT().
+		Stdout(&stdout)
+	suite.mockCmd.EXPECT().
+		Stderr(&stderr)
+	suite.mockCmd.EXPECT().
+		Run().
+		Times(1)
+
+	s := Show{
+		Subcommand: "values",
+		Chart:      "stable/sweet_baby_ray",
+	}
+
+	suite.Require().NoError(s.Prepare(cfg))
+	suite.NoError(s.Execute(cfg))
+}
+
+func (suite *ShowTestSuite) TestPrepareWithShowFlags() {
+	defer suite.ctrl.Finish()
+
+	cfg := Config{
+		Namespace: "barbecue",
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.Equal([]string{"--namespace", "barbecue", "show", "readme",
+			"--version", "1.2.3", "--repo", "https://charts.example.com/",
+			"sweet_baby_ray"}, args)
+
+		return suite.mockCmd
+	}
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+
+	s := Show{
+		Subcommand:   "readme",
+		Chart:        "sweet_baby_ray",
+		ChartVersion: "1.2.3",
+		Repo:         "https://charts.example.com/",
+	}
+
+	suite.Require().NoError(s.Prepare(cfg))
+}
+
+func (suite *ShowTestSuite) TestPrepareWithOCIChart() {
+	defer suite.ctrl.Finish()
+
+	command = func(path string, args ...string) cmd {
+		suite.Equal([]string{"show", "chart", "--version", "0.4.0", "oci://registry.example.com/charts/brisket"}, args)
+
+		return suite.mockCmd
+	}
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+
+	s := Show{
+		Subcommand:   "chart",
+		Chart:        "oci://registry.example.com/charts/brisket",
+		ChartVersion: "0.4.0",
+	}
+	suite.Require().NoError(s.Prepare(Config{}))
+
+	s.Repo = "https://charts.example.com/"
+	suite.EqualError(s.Prepare(Config{}), "chart_repo cannot be used with an OCI chart reference")
+}
+
+func (suite *ShowTestSuite) TestExecuteWithOutputFile() {
+	defer suite.ctrl.Finish()
+
+	dir, err := ioutil.TempDir("", "show")
+	suite.Require().NoError(err)
+	defer os.RemoveAll(dir)
+	outputFile := filepath.Join(dir, "upstream-values.yaml")
+	suite.Require().NoError(ioutil.WriteFile(outputFile, []byte("previous contents"), 0644))
+
+	stdout := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+	}
+
+	suite.mockCmd.EXPECT().Stdout(&stdout)
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+
+	s := Show{
+		Subcommand: "values",
+		Chart:      "stable/sweet_baby_ray",
+		OutputFile: outputFile,
+	}
+
+	suite.Require().NoError(s.Prepare(cfg))
+	contents, err := ioutil.ReadFile(outputFile)
+	suite.Require().NoError(err)
+	suite.Equal("previous contents", string(contents), "Prepare should not touch the output file")
+
+	var output *os.File
+	suite.mockCmd.EXPECT().
+		Stdout(gomock.Any()).
+		Do(func(w interface{}) {
+			output = w.(*os.File)
+			suite.Equal(outputFile, output.Name())
+		})
+	suite.mockCmd.EXPECT().
+		Run().
+		DoAndReturn(func() error {
+			_, err := output.WriteString("replicaCount: 1\n")
+			suite.Require().NoError(err)
+			return fmt.Errorf("the pit is out of charcoal")
+		})
+
+	suite.EqualError(s.Execute(cfg), "the pit is out of charcoal")
+	suite.Error(output.Close(), "the output file should be closed even when helm fails")
+
+	contents, err = ioutil.ReadFile(outputFile)
+	suite.Require().NoError(err)
+	suite.Equal("replicaCount: 1\n", string(contents))
+	suite.Equal("", stdout.String())
+}
+
+func (suite *ShowTestSuite) TestPrepareOutputFileError() {
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+
+	s := Show{
+		Subcommand: "values",
+		Chart:      "stable/sweet_baby_ray",
+		OutputFile: "/nonexistent/directory/values.yaml",
+	}
+
+	suite.EqualError(s.Prepare(Config{}), "output file directory /nonexistent/directory does not exist")
+}
+
+func (suite *ShowTestSuite) TestPrepareDebugFlag() {
+	defer suite.ctrl.Finish()
+
+	stdout := strings.Builder{}
+	stderr := strings.Builder{}
+	cfg := Config{
+		Debug:  true,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.mockCmd.EXPECT().
+			String().
+			Return(fmt.Sprintf("%s %s", path, strings.Join(args, " ")))
+
+		return suite.mockCmd
+	}
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+
+	s := Show{
+		Subcommand: "chart",
+		Chart:      "stable/sweet_baby_ray",
+	}
+
+	suite.Require().NoError(s.Prepare(cfg))
+
+	want := fmt.Sprintf("Generated command: '%s --debug show chart stable/sweet_baby_ray'\n", helmBin)
+	suite.Equal(want, stderr.String())
+	suite.Equal("", stdout.String())
+}
+
+func (suite *ShowTestSuite) TestPrepareRequiresChart() {
+	s := Show{
+		Subcommand: "values",
+	}
+
+	suite.EqualError(s.Prepare(Config{}), "chart is required")
+}
+
+func (suite *ShowTestSuite) TestPrepareRequiresKnownSubcommand() {
+	s := Show{
+		Subcommand: "all",
+		Chart:      "stable/sweet_baby_ray",
+	}
+
+	suite.EqualError(s.Prepare(Config{}), "unknown show subcommand 'all'")
+}
@@ -44,7 +44,7 @@ func (u *Uninstall) Prepare(cfg Config) error {
 	u.cmd.Stderr(cfg.Stderr)
 
 	if cfg.Debug {
-		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", u.cmd.String())
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(u.cmd.String()))
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package run
 
 import (
 	"fmt"
+	"time"
 )
 
 // Upgrade is an execution step that calls `helm upgrade` when executed.
@@ -16,12 +17,27 @@ type Upgrade struct {
 	Timeout      string
 	Force        bool
 
+	HookLogs *HookLogs // If set, print the release's hook logs after upgrading
+
 	cmd cmd
 }
 
 // Execute executes the `helm upgrade` command.
-func (u *Upgrade) Execute(_ Config) error {
-	return u.cmd.Run()
+func (u *Upgrade) Execute(cfg Config) error {
+	if u.HookLogs != nil {
+		u.HookLogs.since = time.Now()
+	}
+	err := u.cmd.Run()
+
+	// Hook logs are most useful when the upgrade failed, so capture them regardless of the outcome. Failing to capture
+	// them shouldn't mask the upgrade's own result, though.
+	if u.HookLogs != nil {
+		if logErr := u.HookLogs.Execute(cfg); logErr != nil {
+			fmt.Fprintf(cfg.Stderr, "could not capture hook logs: %s\n", logErr)
+		}
+	}
+
+	return err
 }
 
 // Prepare gets the Upgrade ready to execute.
@@ -71,14 +87,26 @@ func (u *Upgrade) Prepare(cfg Config) error {
 	for _, vFile := range cfg.ValuesFiles {
 		args = append(args, "--values", vFile)
 	}
+	if cfg.SecretValues != nil {
+		args = append(args, "--values", "/dev/stdin")
+	}
 
 	args = append(args, u.Release, u.Chart)
 	u.cmd = command(helmBin, args...)
 	u.cmd.Stdout(cfg.Stdout)
 	u.cmd.Stderr(cfg.Stderr)
+	if cfg.SecretValues != nil {
+		u.cmd.Stdin(cfg.SecretValues)
+	}
 
 	if cfg.Debug {
-		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", u.cmd.String())
+		fmt.Fprintf(cfg.Stderr, "Generated command: '%s'\n", cfg.censor(u.cmd.String()))
+	}
+
+	if u.HookLogs != nil {
+		if err := u.HookLogs.Prepare(cfg); err != nil {
+			return fmt.Errorf("while preparing to capture hook logs: %w", err)
+		}
 	}
 
 	return nil
@@ -3,9 +3,12 @@ package run
 import (
 	"fmt"
 	"github.com/golang/mock/gomock"
+	"github.com/pelotech/drone-helm3/internal/kube"
+	"github.com/pelotech/drone-helm3/internal/kube/kubetest"
 	"github.com/stretchr/testify/suite"
 	"strings"
 	"testing"
+	"time"
 )
 
 type UpgradeTestSuite struct {
@@ -130,6 +133,133 @@ func (suite *UpgradeTestSuite) TestPrepareWithUpgradeFlags() {
 	suite.Require().Nil(err)
 }
 
+func (suite *UpgradeTestSuite) TestPrepareWithSecretValues() {
+	defer suite.ctrl.Finish()
+
+	secretValues := strings.NewReader("password: hunter2\n")
+	cfg := Config{
+		ValuesFiles:  []string{"/usr/local/stats"},
+		SecretValues: secretValues,
+	}
+
+	u := Upgrade{
+		Chart:   "at40",
+		Release: "jonas_brothers_only_human",
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.Equal([]string{"upgrade", "--install",
+			"--values", "/usr/local/stats",
+			"--values", "/dev/stdin",
+			"jonas_brothers_only_human", "at40"}, args)
+
+		return suite.mockCmd
+	}
+
+	suite.mockCmd.EXPECT().Stdout(gomock.Any())
+	suite.mockCmd.EXPECT().Stderr(gomock.Any())
+	suite.mockCmd.EXPECT().Stdin(secretValues)
+
+	suite.Require().NoError(u.Prepare(cfg))
+}
+
+func (suite *UpgradeTestSuite) TestExecuteCapturesHookLogs() {
+	defer suite.ctrl.Finish()
+
+	origNewClient := newKubeClient
+	newKubeClient = func(cfg kube.Config) (*kube.Client, error) { return kubetest.NewClient("", nil), nil }
+	defer func() { newKubeClient = origNewClient }()
+
+	upgradeCmd := NewMockcmd(suite.ctrl)
+	hooksCmd := NewMockcmd(suite.ctrl)
+	command = func(path string, args ...string) cmd {
+		if args[0] == "upgrade" {
+			return upgradeCmd
+		}
+		suite.Equal([]string{"get", "hooks", "jonas_brothers_only_human"}, args)
+		return hooksCmd
+	}
+
+	stdout := strings.Builder{}
+	stderr := strings.Builder{}
+	cfg := Config{
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+
+	u := Upgrade{
+		Chart:    "at40",
+		Release:  "jonas_brothers_only_human",
+		HookLogs: &HookLogs{Release: "jonas_brothers_only_human"},
+	}
+
+	upgradeCmd.EXPECT().Stdout(&stdout)
+	upgradeCmd.EXPECT().Stderr(&stderr)
+	hooksCmd.EXPECT().Stdout(gomock.Any())
+	hooksCmd.EXPECT().Stderr(&stderr)
+	suite.Require().NoError(u.Prepare(cfg))
+
+	// The hook logs should be captured even if helm upgrade fails, since that's when they're most useful
+	gomock.InOrder(
+		upgradeCmd.EXPECT().
+			Run().
+			Return(fmt.Errorf("pre-upgrade hooks failed")),
+		hooksCmd.EXPECT().
+			Run(),
+	)
+
+	before := time.Now()
+	suite.EqualError(u.Execute(cfg), "pre-upgrade hooks failed")
+	suite.False(u.HookLogs.since.Before(before), "hook pods should be compared against the start of the upgrade")
+}
+
+func (suite *UpgradeTestSuite) TestExecuteReportsHookLogErrors() {
+	defer suite.ctrl.Finish()
+
+	origNewClient := newKubeClient
+	newKubeClient = func(cfg kube.Config) (*kube.Client, error) { return kubetest.NewClient("", nil), nil }
+	defer func() { newKubeClient = origNewClient }()
+
+	stderr := strings.Builder{}
+	cfg := Config{
+		Stderr: &stderr,
+	}
+
+	u := Upgrade{
+		Chart:    "at40",
+		Release:  "jonas_brothers_only_human",
+		HookLogs: &HookLogs{Release: "jonas_brothers_only_human"},
+	}
+
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+	suite.Require().NoError(u.Prepare(cfg))
+
+	gomock.InOrder(
+		suite.mockCmd.EXPECT().Run(),
+		suite.mockCmd.EXPECT().Run().Return(fmt.Errorf("release: not found")),
+	)
+	suite.mockCmd.EXPECT().String().Return("helm get hooks jonas_brothers_only_human")
+
+	suite.NoError(u.Execute(cfg), "failing to capture hook logs shouldn't fail the upgrade")
+	suite.Equal("could not capture hook logs: while running 'helm get hooks jonas_brothers_only_human': release: not found\n", stderr.String())
+}
+
+func (suite *UpgradeTestSuite) TestPrepareHookLogsError() {
+	defer suite.ctrl.Finish()
+
+	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
+	suite.mockCmd.EXPECT().Stderr(gomock.Any()).AnyTimes()
+
+	u := Upgrade{
+		Chart:    "at40",
+		Release:  "jonas_brothers_only_human",
+		HookLogs: &HookLogs{},
+	}
+
+	suite.EqualError(u.Prepare(Config{}), "while preparing to capture hook logs: release is required")
+}
+
 func (suite *UpgradeTestSuite) TestRequiresChartAndRelease() {
 	// These aren't really expected, but allowing them gives clearer test-failure messages
 	suite.mockCmd.EXPECT().Stdout(gomock.Any()).AnyTimes()
@@ -184,3 +314,35 @@ func (suite *UpgradeTestSuite) TestPrepareDebugFlag() {
 	suite.Equal(want, stderr.String())
 	suite.Equal("", stdout.String())
 }
+
+func (suite *UpgradeTestSuite) TestPrepareDebugCensorsSecrets() {
+	u := Upgrade{
+		Chart:   "at40",
+		Release: "lewis_capaldi_someone_you_loved",
+	}
+
+	stderr := strings.Builder{}
+	cfg := Config{
+		Debug:        true,
+		Values:       "lyrics=grace",
+		StringValues: "label=emi,isrc=gbum71900046",
+		Secrets:      []string{"gbum71900046", "lyrics=grace"},
+		Stderr:       &stderr,
+	}
+
+	command = func(path string, args ...string) cmd {
+		suite.mockCmd.EXPECT().
+			String().
+			Return(fmt.Sprintf("%s %s", path, strings.Join(args, " ")))
+
+		return suite.mockCmd
+	}
+	suite.mockCmd.EXPECT().Stdout(gomock.Any())
+	suite.mockCmd.EXPECT().Stderr(&stderr)
+
+	suite.Require().NoError(u.Prepare(cfg))
+
+	want := fmt.Sprintf("Generated command: '%s --debug upgrade --install "+
+		"--set (redacted) --set-string label=emi,isrc=(redacted) lewis_capaldi_someone_you_loved at40'\n", helmBin)
+	suite.Equal(want, stderr.String())
+}
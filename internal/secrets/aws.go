@@ -0,0 +1,193 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const awsService = "secretsmanager"
+
+// AWS is a Provider that reads secrets from AWS Secrets Manager. The path is a secret name or ARN, optionally followed
+// by a key to extract from a JSON-formatted secret, e.g. `prod/my-app#db_password`.
+//
+// The region comes from $AWS_REGION (or $AWS_DEFAULT_REGION). Credentials come from the environment, a web identity
+// token, the ECS container credentials endpoint, or the EC2 instance metadata service; see credentials for details.
+// Endpoint, STSEndpoint, and MetadataEndpoint override the default API locations.
+type AWS struct {
+	Endpoint         string
+	STSEndpoint      string
+	MetadataEndpoint string
+}
+
+type awsCredentials struct {
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+type awsSecretValue struct {
+	SecretString string `json:"SecretString"`
+}
+
+type awsError struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// now is a variable so tests can produce deterministic request signatures.
+var now = time.Now
+
+// Resolve fetches the secret from Secrets Manager and returns either its whole value or the requested key.
+func (a *AWS) Resolve(path string) (string, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return "", errors.New("aws region is required (set $AWS_REGION)")
+	}
+
+	creds, err := a.credentials(region)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", awsService, region)
+	}
+
+	secretID, key := splitKey(path)
+	payload, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signAWSRequest(req, payload, creds, region, awsService, now().UTC())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := awsError{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return "", fmt.Errorf("secrets manager returned status %d: %s %s", resp.StatusCode, e.Type, e.Message)
+	}
+
+	secret := awsSecretValue{}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("could not parse secrets manager response: %w", err)
+	}
+
+	if key == "" {
+		return secret.SecretString, nil
+	}
+
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(secret.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so key '%s' can't be extracted: %w", key, err)
+	}
+	val, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("key '%s' not found", key)
+	}
+	str, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("key '%s' is not a string", key)
+	}
+	return str, nil
+}
+
+// signAWSRequest adds a Signature Version 4 Authorization header to the request.
+func signAWSRequest(req *http.Request, payload []byte, creds awsCredentials, region, service string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, vals := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(vals, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := strings.Builder{}
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(payload),
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(q url.Values) string {
+	// url.Values.Encode sorts by key, but uses + for spaces where SigV4 requires %20.
+	return strings.Replace(q.Encode(), "+", "%20", -1)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
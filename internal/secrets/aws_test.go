@@ -0,0 +1,282 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type AWSTestSuite struct {
+	suite.Suite
+	restore []func()
+	origNow func() time.Time
+}
+
+func TestAWSTestSuite(t *testing.T) {
+	suite.Run(t, new(AWSTestSuite))
+}
+
+func (suite *AWSTestSuite) BeforeTest(_, _ string) {
+	suite.restore = []func(){
+		setenv("AWS_REGION", "us-west-2"),
+		setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE"),
+		setenv("AWS_SECRET_ACCESS_KEY", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"),
+		unsetenv("AWS_SESSION_TOKEN"),
+		unsetenv("AWS_WEB_IDENTITY_TOKEN_FILE"),
+		unsetenv("AWS_ROLE_ARN"),
+		unsetenv("AWS_ROLE_SESSION_NAME"),
+		unsetenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"),
+		unsetenv("AWS_CONTAINER_CREDENTIALS_FULL_URI"),
+		unsetenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"),
+		unsetenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"),
+		unsetenv("AWS_EC2_METADATA_DISABLED"),
+	}
+	suite.origNow = now
+	now = func() time.Time { return time.Date(2019, 12, 10, 8, 30, 0, 0, time.UTC) }
+}
+
+func (suite *AWSTestSuite) AfterTest(_, _ string) {
+	for _, restore := range suite.restore {
+		restore()
+	}
+	now = suite.origNow
+}
+
+func (suite *AWSTestSuite) TestResolve() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal(http.MethodPost, r.Method)
+		suite.Equal("secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		suite.Equal("application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		suite.Equal("20191210T083000Z", r.Header.Get("X-Amz-Date"))
+		suite.Regexp(regexp.MustCompile(`^AWS4-HMAC-SHA256 `+
+			`Credential=AKIDEXAMPLE/20191210/us-west-2/secretsmanager/aws4_request, `+
+			`SignedHeaders=content-type;host;x-amz-date;x-amz-target, `+
+			`Signature=[0-9a-f]{64}$`), r.Header.Get("Authorization"))
+
+		body := make(map[string]string)
+		suite.Require().NoError(json.NewDecoder(r.Body).Decode(&body))
+		suite.Equal(map[string]string{"SecretId": "prod/jaws"}, body)
+
+		fmt.Fprint(w, `{"ARN": "arn:aws:secretsmanager:us-west-2:1:secret:prod/jaws", "SecretString": "bigger boat"}`)
+	}))
+	defer server.Close()
+
+	secret, err := (&AWS{Endpoint: server.URL}).Resolve("prod/jaws")
+	suite.Require().NoError(err)
+	suite.Equal("bigger boat", secret)
+}
+
+func (suite *AWSTestSuite) TestResolveKey() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"SecretString": "{\"boat\": \"bigger\", \"sharks\": 1}"}`)
+	}))
+	defer server.Close()
+
+	a := AWS{Endpoint: server.URL}
+	secret, err := a.Resolve("prod/jaws#boat")
+	suite.Require().NoError(err)
+	suite.Equal("bigger", secret)
+
+	_, err = a.Resolve("prod/jaws#mayor")
+	suite.EqualError(err, "key 'mayor' not found")
+
+	_, err = a.Resolve("prod/jaws#sharks")
+	suite.EqualError(err, "key 'sharks' is not a string")
+}
+
+func (suite *AWSTestSuite) TestResolveIncludesSessionToken() {
+	defer setenv("AWS_SESSION_TOKEN", "we're gonna need a")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("we're gonna need a", r.Header.Get("X-Amz-Security-Token"))
+		suite.Contains(r.Header.Get("Authorization"), "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target,")
+		fmt.Fprint(w, `{"SecretString": "bigger boat"}`)
+	}))
+	defer server.Close()
+
+	_, err := (&AWS{Endpoint: server.URL}).Resolve("prod/jaws")
+	suite.NoError(err)
+}
+
+func (suite *AWSTestSuite) TestResolveErrorStatus() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`)
+	}))
+	defer server.Close()
+
+	_, err := (&AWS{Endpoint: server.URL}).Resolve("prod/orca")
+	suite.EqualError(err, "secrets manager returned status 400: ResourceNotFoundException Secrets Manager can't find the specified secret.")
+}
+
+func (suite *AWSTestSuite) TestResolveRequiresSettings() {
+	defer unsetenv("AWS_REGION")()
+	defer unsetenv("AWS_DEFAULT_REGION")()
+
+	_, err := (&AWS{}).Resolve("prod/jaws")
+	suite.EqualError(err, "aws region is required (set $AWS_REGION)")
+
+	defer setenv("AWS_DEFAULT_REGION", "us-east-1")()
+	defer unsetenv("AWS_SECRET_ACCESS_KEY")()
+
+	_, err = (&AWS{}).Resolve("prod/jaws")
+	suite.EqualError(err, "$AWS_SECRET_ACCESS_KEY is required when $AWS_ACCESS_KEY_ID is set")
+
+	defer unsetenv("AWS_ACCESS_KEY_ID")()
+	defer setenv("AWS_EC2_METADATA_DISABLED", "true")()
+
+	_, err = (&AWS{}).Resolve("prod/jaws")
+	suite.EqualError(err, "no aws credentials found (set $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY, "+
+		"or run with an EKS service account role, ECS task role, or EC2 instance profile)")
+}
+
+func (suite *AWSTestSuite) TestResolveWithWebIdentity() {
+	defer unsetenv("AWS_ACCESS_KEY_ID")()
+	defer unsetenv("AWS_SECRET_ACCESS_KEY")()
+
+	tokenFile, err := ioutil.TempFile("", "token")
+	suite.Require().NoError(err)
+	defer os.Remove(tokenFile.Name())
+	fmt.Fprintln(tokenFile, "eyJhbGciOiJSUzI1NiJ9.amity")
+	tokenFile.Close()
+
+	defer setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile.Name())()
+	defer setenv("AWS_ROLE_ARN", "arn:aws:iam::1:role/brody")()
+
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal(http.MethodPost, r.Method)
+		suite.Require().NoError(r.ParseForm())
+		suite.Equal("AssumeRoleWithWebIdentity", r.PostForm.Get("Action"))
+		suite.Equal("arn:aws:iam::1:role/brody", r.PostForm.Get("RoleArn"))
+		suite.Equal("drone-helm3", r.PostForm.Get("RoleSessionName"))
+		suite.Equal("eyJhbGciOiJSUzI1NiJ9.amity", r.PostForm.Get("WebIdentityToken"))
+		suite.Empty(r.Header.Get("Authorization"))
+
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAQUINT</AccessKeyId>
+      <SecretAccessKey>orca</SecretAccessKey>
+      <SessionToken>indianapolis</SessionToken>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer sts.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Contains(r.Header.Get("Authorization"), "Credential=ASIAQUINT/")
+		suite.Equal("indianapolis", r.Header.Get("X-Amz-Security-Token"))
+		fmt.Fprint(w, `{"SecretString": "bigger boat"}`)
+	}))
+	defer server.Close()
+
+	secret, err := (&AWS{Endpoint: server.URL, STSEndpoint: sts.URL}).Resolve("prod/jaws")
+	suite.Require().NoError(err)
+	suite.Equal("bigger boat", secret)
+}
+
+func (suite *AWSTestSuite) TestResolveWebIdentityError() {
+	defer unsetenv("AWS_ACCESS_KEY_ID")()
+	defer setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "/nonexistent/token")()
+
+	_, err := (&AWS{}).Resolve("prod/jaws")
+	suite.EqualError(err, "could not assume role with web identity: $AWS_ROLE_ARN is required when $AWS_WEB_IDENTITY_TOKEN_FILE is set")
+
+	defer setenv("AWS_ROLE_ARN", "arn:aws:iam::1:role/brody")()
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Fail("sts shouldn't be called without a token")
+	}))
+	defer sts.Close()
+
+	_, err = (&AWS{STSEndpoint: sts.URL}).Resolve("prod/jaws")
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "could not assume role with web identity: open /nonexistent/token")
+}
+
+func (suite *AWSTestSuite) TestResolveWithContainerCredentials() {
+	defer unsetenv("AWS_ACCESS_KEY_ID")()
+	defer setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "amity-island")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/credentials/hooper":
+			suite.Equal("amity-island", r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"AccessKeyId": "ASIAHOOPER", "SecretAccessKey": "tiger", "Token": "shark", "Expiration": "2019-12-10T09:30:00Z"}`)
+		default:
+			suite.Contains(r.Header.Get("Authorization"), "Credential=ASIAHOOPER/")
+			suite.Equal("shark", r.Header.Get("X-Amz-Security-Token"))
+			fmt.Fprint(w, `{"SecretString": "bigger boat"}`)
+		}
+	}))
+	defer server.Close()
+	defer setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/v2/credentials/hooper")()
+
+	secret, err := (&AWS{Endpoint: server.URL}).Resolve("prod/jaws")
+	suite.Require().NoError(err)
+	suite.Equal("bigger boat", secret)
+}
+
+func (suite *AWSTestSuite) TestResolveWithInstanceCredentials() {
+	defer unsetenv("AWS_ACCESS_KEY_ID")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			suite.Equal(http.MethodPut, r.Method)
+			suite.NotEmpty(r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			fmt.Fprint(w, "imds-session")
+		case "/latest/meta-data/iam/security-credentials/":
+			suite.Equal("imds-session", r.Header.Get("X-aws-ec2-metadata-token"))
+			fmt.Fprint(w, "orca-crew\n")
+		case "/latest/meta-data/iam/security-credentials/orca-crew":
+			suite.Equal("imds-session", r.Header.Get("X-aws-ec2-metadata-token"))
+			fmt.Fprint(w, `{"Code": "Success", "AccessKeyId": "ASIABRODY", "SecretAccessKey": "chief", "Token": "police"}`)
+		default:
+			suite.Contains(r.Header.Get("Authorization"), "Credential=ASIABRODY/")
+			suite.Equal("police", r.Header.Get("X-Amz-Security-Token"))
+			fmt.Fprint(w, `{"SecretString": "bigger boat"}`)
+		}
+	}))
+	defer server.Close()
+
+	secret, err := (&AWS{Endpoint: server.URL, MetadataEndpoint: server.URL}).Resolve("prod/jaws")
+	suite.Require().NoError(err)
+	suite.Equal("bigger boat", secret)
+}
+
+func (suite *AWSTestSuite) TestResolveInstanceMetadataUnavailable() {
+	defer unsetenv("AWS_ACCESS_KEY_ID")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := (&AWS{MetadataEndpoint: server.URL}).Resolve("prod/jaws")
+	suite.EqualError(err, "no aws credentials found (set $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY, "+
+		"or run with an EKS service account role, ECS task role, or EC2 instance profile): "+
+		"the instance metadata service was unavailable: PUT /latest/api/token returned status 404")
+}
+
+// TestSignAWSRequest checks the signer against the "get-vanilla" case from AWS's published SigV4 test suite.
+func (suite *AWSTestSuite) TestSignAWSRequest() {
+	creds := awsCredentials{accessKey: "AKIDEXAMPLE", secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	suite.Require().NoError(err)
+
+	signAWSRequest(req, []byte{}, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	suite.Equal("20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	suite.Equal("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31", req.Header.Get("Authorization"))
+}
@@ -0,0 +1,245 @@
+package secrets
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	awsContainerEndpoint = "http://169.254.170.2"
+	awsMetadataEndpoint  = "http://169.254.169.254"
+)
+
+var errNoAWSCredentials = errors.New("no aws credentials found (set $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY, " +
+	"or run with an EKS service account role, ECS task role, or EC2 instance profile)")
+
+// awsRoleCredentials is the credentials document returned by the ECS container endpoint and the EC2 metadata service.
+type awsRoleCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+}
+
+type awsWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string `xml:"AccessKeyId"`
+		SecretAccessKey string `xml:"SecretAccessKey"`
+		SessionToken    string `xml:"SessionToken"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+type awsWebIdentityError struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// credentials looks for credentials in the same places as the AWS SDKs, in order: static keys in the environment, a
+// web identity token (EKS's IAM roles for service accounts), the ECS container credentials endpoint (which EKS Pod
+// Identity also uses), and the EC2 instance metadata service.
+func (a *AWS) credentials(region string) (awsCredentials, error) {
+	if accessKey := os.Getenv("AWS_ACCESS_KEY_ID"); accessKey != "" {
+		secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+		if secretKey == "" {
+			return awsCredentials{}, errors.New("$AWS_SECRET_ACCESS_KEY is required when $AWS_ACCESS_KEY_ID is set")
+		}
+		return awsCredentials{
+			accessKey:    accessKey,
+			secretKey:    secretKey,
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	if tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); tokenFile != "" {
+		creds, err := a.webIdentityCredentials(tokenFile, region)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("could not assume role with web identity: %w", err)
+		}
+		return creds, nil
+	}
+
+	if uri := containerCredentialsURI(); uri != "" {
+		creds, err := containerCredentials(uri)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("could not get credentials from the container credentials endpoint: %w", err)
+		}
+		return creds, nil
+	}
+
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return awsCredentials{}, errNoAWSCredentials
+	}
+	creds, err := a.instanceCredentials()
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("%s: the instance metadata service was unavailable: %w", errNoAWSCredentials, err)
+	}
+	return creds, nil
+}
+
+// webIdentityCredentials exchanges the token in $AWS_WEB_IDENTITY_TOKEN_FILE for credentials for $AWS_ROLE_ARN.
+func (a *AWS) webIdentityCredentials(tokenFile, region string) (awsCredentials, error) {
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	if roleARN == "" {
+		return awsCredentials{}, errors.New("$AWS_ROLE_ARN is required when $AWS_WEB_IDENTITY_TOKEN_FILE is set")
+	}
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = "drone-helm3"
+	}
+
+	token, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	endpoint := a.STSEndpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+	}
+
+	// AssumeRoleWithWebIdentity is authenticated by the token itself, so the request isn't signed.
+	form := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	resp, err := httpClient.PostForm(endpoint, form)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := awsWebIdentityError{}
+		xml.NewDecoder(resp.Body).Decode(&e)
+		return awsCredentials{}, fmt.Errorf("sts returned status %d: %s %s", resp.StatusCode, e.Code, e.Message)
+	}
+
+	body := awsWebIdentityResponse{}
+	if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return awsCredentials{}, fmt.Errorf("could not parse sts response: %w", err)
+	}
+	return awsCredentials{
+		accessKey:    body.Credentials.AccessKeyID,
+		secretKey:    body.Credentials.SecretAccessKey,
+		sessionToken: body.Credentials.SessionToken,
+	}, nil
+}
+
+func containerCredentialsURI() string {
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		return awsContainerEndpoint + relative
+	}
+	return os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+}
+
+// containerCredentials fetches the task or pod role's credentials from the ECS container credentials endpoint.
+func containerCredentials(uri string) (awsCredentials, error) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		contents, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		token = strings.TrimSpace(string(contents))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	return fetchRoleCredentials(req)
+}
+
+// instanceCredentials fetches the instance profile's credentials from the EC2 instance metadata service, using an
+// IMDSv2 session token.
+func (a *AWS) instanceCredentials() (awsCredentials, error) {
+	endpoint := a.MetadataEndpoint
+	if endpoint == "" {
+		endpoint = awsMetadataEndpoint
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+
+	req, err := http.NewRequest(http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	token, err := metadataString(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	credsURL := endpoint + "/latest/meta-data/iam/security-credentials/"
+	req, err = http.NewRequest(http.MethodGet, credsURL, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	roles, err := metadataString(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	role := strings.TrimSpace(strings.SplitN(roles, "\n", 2)[0])
+	if role == "" {
+		return awsCredentials{}, errors.New("the instance has no instance profile")
+	}
+
+	req, err = http.NewRequest(http.MethodGet, credsURL+role, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	return fetchRoleCredentials(req)
+}
+
+func metadataString(req *http.Request) (string, error) {
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s returned status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func fetchRoleCredentials(req *http.Request) (awsCredentials, error) {
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return awsCredentials{}, fmt.Errorf("%s %s returned status %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+
+	body := awsRoleCredentials{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return awsCredentials{}, fmt.Errorf("could not parse credentials: %w", err)
+	}
+	if body.AccessKeyID == "" || body.SecretAccessKey == "" {
+		return awsCredentials{}, errors.New("the credentials response was empty")
+	}
+	return awsCredentials{
+		accessKey:    body.AccessKeyID,
+		secretKey:    body.SecretAccessKey,
+		sessionToken: body.Token,
+	}, nil
+}
@@ -0,0 +1,18 @@
+package secrets
+
+import (
+	"fmt"
+	"os"
+)
+
+// Env is a Provider that reads secrets from environment variables. The path is the variable name.
+type Env struct{}
+
+// Resolve returns the value of the named environment variable.
+func (e *Env) Resolve(path string) (string, error) {
+	val, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", path)
+	}
+	return val, nil
+}
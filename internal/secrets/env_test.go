@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+type EnvTestSuite struct {
+	suite.Suite
+}
+
+func TestEnvTestSuite(t *testing.T) {
+	suite.Run(t, new(EnvTestSuite))
+}
+
+func (suite *EnvTestSuite) TestResolve() {
+	defer setenv("DRONE_HELM3_TEST_SECRET", "the sound of one hand clapping")()
+
+	secret, err := (&Env{}).Resolve("DRONE_HELM3_TEST_SECRET")
+	suite.Require().NoError(err)
+	suite.Equal("the sound of one hand clapping", secret)
+}
+
+func (suite *EnvTestSuite) TestResolveAllowsEmptyValue() {
+	defer setenv("DRONE_HELM3_TEST_SECRET", "")()
+
+	secret, err := (&Env{}).Resolve("DRONE_HELM3_TEST_SECRET")
+	suite.Require().NoError(err)
+	suite.Equal("", secret)
+}
+
+func (suite *EnvTestSuite) TestResolveUnsetVariable() {
+	defer unsetenv("DRONE_HELM3_TEST_SECRET")()
+
+	_, err := (&Env{}).Resolve("DRONE_HELM3_TEST_SECRET")
+	suite.EqualError(err, "environment variable DRONE_HELM3_TEST_SECRET is not set")
+}
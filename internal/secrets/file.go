@@ -0,0 +1,19 @@
+package secrets
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// File is a Provider that reads secrets from files, such as those mounted by drone's secret plugins. The path is
+// relative to the working directory unless it begins with a slash (i.e. `secret://file//run/secrets/token`).
+type File struct{}
+
+// Resolve returns the contents of the file, minus any trailing newlines.
+func (f *File) Resolve(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(contents), "\r\n"), nil
+}
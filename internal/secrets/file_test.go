@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+type FileTestSuite struct {
+	suite.Suite
+}
+
+func TestFileTestSuite(t *testing.T) {
+	suite.Run(t, new(FileTestSuite))
+}
+
+func (suite *FileTestSuite) TestResolveTrimsTrailingNewlines() {
+	file, err := ioutil.TempFile("", "secret********")
+	suite.Require().NoError(err)
+	defer os.Remove(file.Name())
+
+	_, err = file.WriteString("  eleven herbs and spices\n\n")
+	suite.Require().NoError(err)
+	suite.Require().NoError(file.Close())
+
+	secret, err := (&File{}).Resolve(file.Name())
+	suite.Require().NoError(err)
+	suite.Equal("  eleven herbs and spices", secret)
+}
+
+func (suite *FileTestSuite) TestResolveMissingFile() {
+	_, err := (&File{}).Resolve("/usr/local/kfc/recipe.txt")
+	suite.Error(err)
+	suite.True(os.IsNotExist(err))
+}
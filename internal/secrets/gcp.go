@@ -0,0 +1,147 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	gcpEndpoint         = "https://secretmanager.googleapis.com"
+	gcpMetadataEndpoint = "http://metadata.google.internal"
+)
+
+// GCP is a Provider that reads secrets from Google Cloud Secret Manager. The path is the secret's resource name, e.g.
+// `projects/my-project/secrets/db-password/versions/3`. If the version is omitted, the latest version is used.
+//
+// The access token comes from $GOOGLE_OAUTH_ACCESS_TOKEN if it's set, then from the service account key or user
+// credentials file named by $GOOGLE_APPLICATION_CREDENTIALS, and otherwise from the GCE metadata server. Endpoint and
+// MetadataEndpoint override the default API locations.
+type GCP struct {
+	Endpoint         string
+	MetadataEndpoint string
+}
+
+type gcpAccessResponse struct {
+	Payload struct {
+		Data string `json:"data"`
+	} `json:"payload"`
+}
+
+type gcpErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type gcpTokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// Resolve fetches the secret version from Secret Manager and returns its payload.
+func (g *GCP) Resolve(path string) (string, error) {
+	name := strings.Trim(path, "/")
+	if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/secrets/") {
+		return "", fmt.Errorf("bad secret name '%s' (must look like projects/PROJECT/secrets/SECRET)", name)
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	token, err := g.accessToken()
+	if err != nil {
+		return "", fmt.Errorf("could not get gcp access token: %w", err)
+	}
+
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = gcpEndpoint
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v1/%s:access", strings.TrimRight(endpoint, "/"), name), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := gcpErrorResponse{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return "", fmt.Errorf("secret manager returned status %d: %s", resp.StatusCode, e.Error.Message)
+	}
+
+	body := gcpAccessResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("could not parse secret manager response: %w", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("could not decode secret payload: %w", err)
+	}
+	return string(data), nil
+}
+
+func (g *GCP) accessToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		token, err := credentialsFileToken(path)
+		if err != nil {
+			return "", fmt.Errorf("while using credentials file %s: %w", path, err)
+		}
+		return token, nil
+	}
+
+	token, err := g.metadataToken()
+	if err != nil {
+		return "", fmt.Errorf("metadata server lookup failed "+
+			"(set $GOOGLE_APPLICATION_CREDENTIALS or $GOOGLE_OAUTH_ACCESS_TOKEN if not running on GCP): %w", err)
+	}
+	return token, nil
+}
+
+func (g *GCP) metadataToken() (string, error) {
+
+	endpoint := g.MetadataEndpoint
+	if endpoint == "" {
+		endpoint = gcpMetadataEndpoint
+	}
+
+	url := strings.TrimRight(endpoint, "/") + "/computeMetadata/v1/instance/service-accounts/default/token"
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned status %d", resp.StatusCode)
+	}
+
+	body := gcpTokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("metadata server returned an empty token")
+	}
+	return body.AccessToken, nil
+}
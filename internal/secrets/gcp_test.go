@@ -0,0 +1,243 @@
+package secrets
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type GCPTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func TestGCPTestSuite(t *testing.T) {
+	suite.Run(t, new(GCPTestSuite))
+}
+
+func (suite *GCPTestSuite) BeforeTest(_, _ string) {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "gcp")
+	suite.Require().NoError(err)
+}
+
+func (suite *GCPTestSuite) AfterTest(_, _ string) {
+	os.RemoveAll(suite.dir)
+}
+
+func (suite *GCPTestSuite) TestResolveWithEnvironmentToken() {
+	defer setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.tardis")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal(http.MethodGet, r.Method)
+		suite.Equal("/v1/projects/gallifrey/secrets/name/versions/7:access", r.URL.Path)
+		suite.Equal("Bearer ya29.tardis", r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"name": "projects/gallifrey/secrets/name/versions/7", "payload": {"data": "ZG9jdG9yIHdobz8="}}`)
+	}))
+	defer server.Close()
+
+	secret, err := (&GCP{Endpoint: server.URL}).Resolve("projects/gallifrey/secrets/name/versions/7")
+	suite.Require().NoError(err)
+	suite.Equal("doctor who?", secret)
+}
+
+func (suite *GCPTestSuite) TestResolveDefaultsToLatestVersion() {
+	defer setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.tardis")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("/v1/projects/gallifrey/secrets/name/versions/latest:access", r.URL.Path)
+		fmt.Fprint(w, `{"payload": {"data": "ZG9jdG9yIHdobz8="}}`)
+	}))
+	defer server.Close()
+
+	_, err := (&GCP{Endpoint: server.URL}).Resolve("projects/gallifrey/secrets/name")
+	suite.NoError(err)
+}
+
+func (suite *GCPTestSuite) TestResolveWithMetadataToken() {
+	defer unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")()
+	defer unsetenv("GOOGLE_APPLICATION_CREDENTIALS")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			suite.Equal("Google", r.Header.Get("Metadata-Flavor"))
+			fmt.Fprint(w, `{"access_token": "ya29.metadata", "expires_in": 3599, "token_type": "Bearer"}`)
+		default:
+			suite.Equal("Bearer ya29.metadata", r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"payload": {"data": "ZG9jdG9yIHdobz8="}}`)
+		}
+	}))
+	defer server.Close()
+
+	g := GCP{Endpoint: server.URL, MetadataEndpoint: server.URL}
+	secret, err := g.Resolve("projects/gallifrey/secrets/name")
+	suite.Require().NoError(err)
+	suite.Equal("doctor who?", secret)
+}
+
+func (suite *GCPTestSuite) TestResolveMetadataServerTimeout() {
+	defer unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")()
+	defer unsetenv("GOOGLE_APPLICATION_CREDENTIALS")()
+
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	origClient := metadataClient
+	metadataClient = &http.Client{Timeout: 10 * time.Millisecond}
+	defer func() { metadataClient = origClient }()
+
+	_, err := (&GCP{Endpoint: server.URL, MetadataEndpoint: server.URL}).Resolve("projects/gallifrey/secrets/name")
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "set $GOOGLE_APPLICATION_CREDENTIALS or $GOOGLE_OAUTH_ACCESS_TOKEN if not running on GCP")
+}
+
+func (suite *GCPTestSuite) TestResolveWithServiceAccountKey() {
+	defer unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	suite.Require().NoError(err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			suite.Require().NoError(r.ParseForm())
+			suite.Equal("urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+			parts := strings.Split(r.PostForm.Get("assertion"), ".")
+			suite.Require().Len(parts, 3)
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			suite.Require().NoError(err)
+			sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			suite.NoError(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, sum[:], signature))
+
+			claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+			suite.Require().NoError(err)
+			claims := make(map[string]interface{})
+			suite.Require().NoError(json.Unmarshal(claimsJSON, &claims))
+			suite.Equal("companion@gallifrey.iam.gserviceaccount.com", claims["iss"])
+			suite.Equal("https://www.googleapis.com/auth/cloud-platform", claims["scope"])
+			suite.Equal("http://"+r.Host+"/token", claims["aud"])
+
+			fmt.Fprint(w, `{"access_token": "ya29.companion", "expires_in": 3599, "token_type": "Bearer"}`)
+		default:
+			suite.Equal("Bearer ya29.companion", r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"payload": {"data": "ZG9jdG9yIHdobz8="}}`)
+		}
+	}))
+	defer server.Close()
+
+	credentials, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "companion@gallifrey.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	suite.Require().NoError(err)
+	defer setenv("GOOGLE_APPLICATION_CREDENTIALS", suite.writeCredentials(credentials))()
+
+	secret, err := (&GCP{Endpoint: server.URL}).Resolve("projects/gallifrey/secrets/name")
+	suite.Require().NoError(err)
+	suite.Equal("doctor who?", secret)
+}
+
+func (suite *GCPTestSuite) TestResolveWithUserCredentials() {
+	defer unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			suite.Require().NoError(r.ParseForm())
+			suite.Equal("refresh_token", r.PostForm.Get("grant_type"))
+			suite.Equal("rose", r.PostForm.Get("client_id"))
+			suite.Equal("bad-wolf", r.PostForm.Get("client_secret"))
+			suite.Equal("1//regenerate", r.PostForm.Get("refresh_token"))
+			fmt.Fprint(w, `{"access_token": "ya29.rose"}`)
+		default:
+			suite.Equal("Bearer ya29.rose", r.Header.Get("Authorization"))
+			fmt.Fprint(w, `{"payload": {"data": "ZG9jdG9yIHdobz8="}}`)
+		}
+	}))
+	defer server.Close()
+
+	credentials := fmt.Sprintf(`{"type": "authorized_user", "client_id": "rose", "client_secret": "bad-wolf", `+
+		`"refresh_token": "1//regenerate", "token_uri": "%s/token"}`, server.URL)
+	defer setenv("GOOGLE_APPLICATION_CREDENTIALS", suite.writeCredentials([]byte(credentials)))()
+
+	secret, err := (&GCP{Endpoint: server.URL}).Resolve("projects/gallifrey/secrets/name")
+	suite.Require().NoError(err)
+	suite.Equal("doctor who?", secret)
+}
+
+func (suite *GCPTestSuite) TestResolveCredentialsFileErrors() {
+	defer unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")()
+
+	path := suite.writeCredentials([]byte(`{"type": "external_account"}`))
+	defer setenv("GOOGLE_APPLICATION_CREDENTIALS", path)()
+
+	_, err := (&GCP{}).Resolve("projects/gallifrey/secrets/name")
+	suite.EqualError(err, fmt.Sprintf("could not get gcp access token: while using credentials file %s: "+
+		"unsupported credentials type 'external_account' (must be service_account or authorized_user)", path))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`)
+	}))
+	defer server.Close()
+
+	path = suite.writeCredentials([]byte(fmt.Sprintf(`{"type": "authorized_user", "token_uri": "%s"}`, server.URL)))
+	defer setenv("GOOGLE_APPLICATION_CREDENTIALS", path)()
+
+	_, err = (&GCP{}).Resolve("projects/gallifrey/secrets/name")
+	suite.EqualError(err, fmt.Sprintf("could not get gcp access token: while using credentials file %s: "+
+		"token endpoint returned status 400: invalid_grant Token has been expired or revoked.", path))
+}
+
+func (suite *GCPTestSuite) TestResolveErrorStatus() {
+	defer setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "ya29.tardis")()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error": {"code": 404, "message": "Secret [name] not found or has no versions."}}`)
+	}))
+	defer server.Close()
+
+	_, err := (&GCP{Endpoint: server.URL}).Resolve("projects/gallifrey/secrets/name")
+	suite.EqualError(err, "secret manager returned status 404: Secret [name] not found or has no versions.")
+}
+
+func (suite *GCPTestSuite) TestResolveBadName() {
+	_, err := (&GCP{}).Resolve("gallifrey/name")
+	suite.EqualError(err, "bad secret name 'gallifrey/name' (must look like projects/PROJECT/secrets/SECRET)")
+}
+
+// writeCredentials writes a credentials file into the test's temporary directory.
+func (suite *GCPTestSuite) writeCredentials(contents []byte) string {
+	file, err := ioutil.TempFile(suite.dir, "credentials*.json")
+	suite.Require().NoError(err)
+	defer file.Close()
+
+	_, err = file.Write(contents)
+	suite.Require().NoError(err)
+	return file.Name()
+}
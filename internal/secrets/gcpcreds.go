@@ -0,0 +1,139 @@
+package secrets
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const (
+	gcpTokenURI = "https://oauth2.googleapis.com/token"
+	gcpScope    = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// gcpCredentialsFile covers the two kinds of file that $GOOGLE_APPLICATION_CREDENTIALS usually names: a service
+// account key, or the user credentials written by `gcloud auth application-default login`.
+type gcpCredentialsFile struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type gcpOAuthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// credentialsFileToken exchanges the credentials in a service account key or user credentials file for an access token.
+func credentialsFileToken(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	creds := gcpCredentialsFile{}
+	if err := json.Unmarshal(contents, &creds); err != nil {
+		return "", fmt.Errorf("could not parse credentials: %w", err)
+	}
+
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = gcpTokenURI
+	}
+
+	var form url.Values
+	switch creds.Type {
+	case "service_account":
+		assertion, err := serviceAccountAssertion(creds, tokenURI)
+		if err != nil {
+			return "", err
+		}
+		form = url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+	case "authorized_user":
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		}
+	default:
+		return "", fmt.Errorf("unsupported credentials type '%s' (must be service_account or authorized_user)", creds.Type)
+	}
+
+	resp, err := httpClient.PostForm(tokenURI, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		e := gcpOAuthError{}
+		json.NewDecoder(resp.Body).Decode(&e)
+		return "", fmt.Errorf("token endpoint returned status %d: %s %s", resp.StatusCode, e.Error, e.Description)
+	}
+
+	body := gcpTokenResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("could not parse token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("token endpoint returned an empty token")
+	}
+	return body.AccessToken, nil
+}
+
+// serviceAccountAssertion builds the signed JWT that a service account exchanges for an access token.
+func serviceAccountAssertion(creds gcpCredentialsFile, tokenURI string) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", errors.New("private_key is not a PEM-encoded key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not parse private_key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("private_key is not an RSA key")
+	}
+
+	issued := now().Unix()
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": gcpScope,
+		"aud":   tokenURI,
+		"iat":   issued,
+		"exp":   issued + 3600,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	signingInput := encoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + encoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encoding.EncodeToString(signature), nil
+}
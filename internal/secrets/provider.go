@@ -0,0 +1,87 @@
+package secrets
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Scheme is the prefix that marks a setting value as a reference to a secret, e.g. `secret://vault/secret/data/app#token`.
+const Scheme = "secret://"
+
+// A Provider looks up secret values in a particular backend.
+type Provider interface {
+	// Resolve returns the secret stored at the given path. The meaning of the path is provider-specific.
+	Resolve(path string) (string, error)
+}
+
+// A Resolver dispatches secret references to the Provider registered under the reference's provider name.
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// httpClient is shared by the providers that talk to a remote API. It's a variable so tests can replace it.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// metadataClient is used for cloud metadata and credentials endpoints. It has a short timeout so that looking for one
+// fails quickly when not running on that cloud. It's a variable so tests can replace it.
+var metadataClient = &http.Client{Timeout: 2 * time.Second}
+
+// NewResolver creates a Resolver with all of drone-helm3's built-in providers registered.
+func NewResolver() *Resolver {
+	r := &Resolver{providers: make(map[string]Provider)}
+	r.Register("env", &Env{})
+	r.Register("file", &File{})
+	r.Register("vault", &Vault{})
+	r.Register("aws", &AWS{})
+	r.Register("gcp", &GCP{})
+
+	return r
+}
+
+// Register makes a Provider available under the given name, replacing any provider previously registered with that name.
+func (r *Resolver) Register(name string, p Provider) {
+	r.providers[name] = p
+}
+
+// IsReference reports whether the value is a secret reference rather than a literal.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// Resolve returns the secret referred to by a `secret://provider/path` value. Values without the secret:// prefix are
+// returned unchanged.
+func (r *Resolver) Resolve(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+
+	split := strings.SplitN(strings.TrimPrefix(value, Scheme), "/", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return "", fmt.Errorf("bad secret reference '%s'", value)
+	}
+
+	name := split[0]
+	path := split[1]
+
+	p, ok := r.providers[name]
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider '%s'", name)
+	}
+
+	secret, err := p.Resolve(path)
+	if err != nil {
+		return "", fmt.Errorf("while resolving %s secret '%s': %w", name, path, err)
+	}
+	return secret, nil
+}
+
+// splitKey separates an optional `#key` suffix from a secret path.
+func splitKey(path string) (string, string) {
+	split := strings.SplitN(path, "#", 2)
+	if len(split) == 2 {
+		return split[0], split[1]
+	}
+	return path, ""
+}
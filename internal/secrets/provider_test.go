@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"testing"
+)
+
+type ResolverTestSuite struct {
+	suite.Suite
+}
+
+type stubProvider struct {
+	paths  []string
+	secret string
+	err    error
+}
+
+func (s *stubProvider) Resolve(path string) (string, error) {
+	s.paths = append(s.paths, path)
+	return s.secret, s.err
+}
+
+func TestResolverTestSuite(t *testing.T) {
+	suite.Run(t, new(ResolverTestSuite))
+}
+
+func (suite *ResolverTestSuite) TestNewResolverRegistersBuiltins() {
+	r := NewResolver()
+	suite.IsType(&Env{}, r.providers["env"])
+	suite.IsType(&File{}, r.providers["file"])
+	suite.IsType(&Vault{}, r.providers["vault"])
+	suite.IsType(&AWS{}, r.providers["aws"])
+	suite.IsType(&GCP{}, r.providers["gcp"])
+}
+
+func (suite *ResolverTestSuite) TestResolveDispatchesToProvider() {
+	stub := &stubProvider{secret: "the owls are not what they seem"}
+	r := NewResolver()
+	r.Register("log_lady", stub)
+
+	secret, err := r.Resolve("secret://log_lady/great/northern#hotel")
+	suite.Require().NoError(err)
+	suite.Equal("the owls are not what they seem", secret)
+	suite.Equal([]string{"great/northern#hotel"}, stub.paths)
+}
+
+func (suite *ResolverTestSuite) TestResolvePassesLiteralsThrough() {
+	stub := &stubProvider{}
+	r := NewResolver()
+	r.Register("env", stub)
+
+	val, err := r.Resolve("damn fine coffee")
+	suite.Require().NoError(err)
+	suite.Equal("damn fine coffee", val)
+
+	val, err = r.Resolve("env/SECRET")
+	suite.Require().NoError(err)
+	suite.Equal("env/SECRET", val)
+
+	suite.Empty(stub.paths)
+}
+
+func (suite *ResolverTestSuite) TestResolveBadReference() {
+	r := NewResolver()
+
+	_, err := r.Resolve("secret://env")
+	suite.EqualError(err, "bad secret reference 'secret://env'")
+
+	_, err = r.Resolve("secret://env/")
+	suite.EqualError(err, "bad secret reference 'secret://env/'")
+
+	_, err = r.Resolve("secret:///SECRET")
+	suite.EqualError(err, "bad secret reference 'secret:///SECRET'")
+}
+
+func (suite *ResolverTestSuite) TestResolveUnknownProvider() {
+	_, err := NewResolver().Resolve("secret://black_lodge/cooper")
+	suite.EqualError(err, "unknown secret provider 'black_lodge'")
+}
+
+func (suite *ResolverTestSuite) TestResolveWrapsProviderErrors() {
+	r := NewResolver()
+	r.Register("log_lady", &stubProvider{err: errors.New("the log is silent")})
+
+	_, err := r.Resolve("secret://log_lady/pine")
+	suite.EqualError(err, "while resolving log_lady secret 'pine': the log is silent")
+}
+
+// setenv sets an environment variable and returns a function that restores its original state.
+func setenv(key, val string) func() {
+	orig, ok := os.LookupEnv(key)
+	os.Setenv(key, val)
+	return func() {
+		if ok {
+			os.Setenv(key, orig)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// unsetenv unsets an environment variable and returns a function that restores its original state.
+func unsetenv(key string) func() {
+	orig, ok := os.LookupEnv(key)
+	os.Unsetenv(key)
+	return func() {
+		if ok {
+			os.Setenv(key, orig)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Vault is a Provider that reads secrets from a HashiCorp Vault server. The path is the API path of a KV secret
+// followed by the key to extract, e.g. `secret/data/my-app#db_password`. Both v1 and v2 KV engines are supported.
+//
+// Address and Token default to the standard $VAULT_ADDR and $VAULT_TOKEN variables.
+type Vault struct {
+	Address string
+	Token   string
+}
+
+type vaultResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []string               `json:"errors"`
+}
+
+// Resolve fetches the secret from Vault and returns the requested key.
+func (v *Vault) Resolve(path string) (string, error) {
+	address := v.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	token := v.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+
+	if address == "" {
+		return "", errors.New("vault address is required (set $VAULT_ADDR)")
+	}
+	if token == "" {
+		return "", errors.New("vault token is required (set $VAULT_TOKEN)")
+	}
+
+	secretPath, key := splitKey(path)
+	if key == "" {
+		return "", fmt.Errorf("vault path must specify a key, e.g. '%s#password'", secretPath)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s", strings.TrimRight(address, "/"), strings.TrimLeft(secretPath, "/"))
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := os.Getenv("VAULT_NAMESPACE"); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body := vaultResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("could not parse vault response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+	}
+
+	data := body.Data
+	// The v2 KV engine nests the secret's contents inside a second "data" object, alongside its metadata.
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = nested
+		}
+	}
+
+	val, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("key '%s' not found", key)
+	}
+	str, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("key '%s' is not a string", key)
+	}
+	return str, nil
+}
@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type VaultTestSuite struct {
+	suite.Suite
+}
+
+func TestVaultTestSuite(t *testing.T) {
+	suite.Run(t, new(VaultTestSuite))
+}
+
+func (suite *VaultTestSuite) TestResolveKVv2() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal(http.MethodGet, r.Method)
+		suite.Equal("/v1/secret/data/vault-111", r.URL.Path)
+		suite.Equal("hunter2", r.Header.Get("X-Vault-Token"))
+		fmt.Fprint(w, `{"data": {"data": {"combination": "12345"}, "metadata": {"version": 3}}}`)
+	}))
+	defer server.Close()
+
+	v := Vault{Address: server.URL, Token: "hunter2"}
+	secret, err := v.Resolve("secret/data/vault-111#combination")
+	suite.Require().NoError(err)
+	suite.Equal("12345", secret)
+}
+
+func (suite *VaultTestSuite) TestResolveKVv1() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("/v1/kv/vault-111", r.URL.Path)
+		fmt.Fprint(w, `{"data": {"combination": "12345"}}`)
+	}))
+	defer server.Close()
+
+	v := Vault{Address: server.URL + "/", Token: "hunter2"}
+	secret, err := v.Resolve("kv/vault-111#combination")
+	suite.Require().NoError(err)
+	suite.Equal("12345", secret)
+}
+
+func (suite *VaultTestSuite) TestResolveUsesEnvironment() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Equal("from the environment", r.Header.Get("X-Vault-Token"))
+		suite.Equal("fallout", r.Header.Get("X-Vault-Namespace"))
+		fmt.Fprint(w, `{"data": {"combination": "12345"}}`)
+	}))
+	defer server.Close()
+
+	defer setenv("VAULT_ADDR", server.URL)()
+	defer setenv("VAULT_TOKEN", "from the environment")()
+	defer setenv("VAULT_NAMESPACE", "fallout")()
+
+	secret, err := (&Vault{}).Resolve("kv/vault-111#combination")
+	suite.Require().NoError(err)
+	suite.Equal("12345", secret)
+}
+
+func (suite *VaultTestSuite) TestResolveErrorStatus() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"errors": ["permission denied"]}`)
+	}))
+	defer server.Close()
+
+	v := Vault{Address: server.URL, Token: "hunter2"}
+	_, err := v.Resolve("kv/vault-111#combination")
+	suite.EqualError(err, "vault returned status 403: permission denied")
+}
+
+func (suite *VaultTestSuite) TestResolveMissingKey() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"combination": "12345", "overseer": 1}}`)
+	}))
+	defer server.Close()
+
+	v := Vault{Address: server.URL, Token: "hunter2"}
+	_, err := v.Resolve("kv/vault-111#password")
+	suite.EqualError(err, "key 'password' not found")
+
+	_, err = v.Resolve("kv/vault-111#overseer")
+	suite.EqualError(err, "key 'overseer' is not a string")
+}
+
+func (suite *VaultTestSuite) TestResolveRequiresSettings() {
+	defer unsetenv("VAULT_ADDR")()
+	defer unsetenv("VAULT_TOKEN")()
+
+	_, err := (&Vault{}).Resolve("kv/vault-111#combination")
+	suite.EqualError(err, "vault address is required (set $VAULT_ADDR)")
+
+	_, err = (&Vault{Address: "https://vault.example.com"}).Resolve("kv/vault-111#combination")
+	suite.EqualError(err, "vault token is required (set $VAULT_TOKEN)")
+
+	_, err = (&Vault{Address: "https://vault.example.com", Token: "hunter2"}).Resolve("kv/vault-111")
+	suite.EqualError(err, "vault path must specify a key, e.g. 'kv/vault-111#password'")
+}